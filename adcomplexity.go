@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	adSpecialChars       = "~!@#$%^&*_-+=`|\\(){}[]:;\"'<>,.?/"
+	adDisplayNameDelims  = ",.-_# \t"
+	adMinCategories      = 3
+	adMinLength          = 6
+	adMinSubstringLength = 3
+)
+
+func adCategories(password string) int {
+	var upper, lower, digit, special, other bool
+	for _, r := range password {
+		switch {
+		case r >= '0' && r <= '9':
+			digit = true
+		case strings.ContainsRune(adSpecialChars, r):
+			special = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsLetter(r):
+			other = true
+		}
+	}
+	count := 0
+	for _, present := range []bool{upper, lower, digit, special, other} {
+		if present {
+			count++
+		}
+	}
+	return count
+}
+
+func adComplexityViolations(password string, username string, displayName string) []string {
+	var violations []string
+	if categories := adCategories(password); categories < adMinCategories {
+		violations = append(violations, fmt.Sprintf("only %d of the 5 AD character categories, at least %d required", categories, adMinCategories))
+	}
+	lowered := strings.ToLower(password)
+	if len(username) >= adMinSubstringLength && strings.Contains(lowered, strings.ToLower(username)) {
+		violations = append(violations, "contains the username")
+	}
+	tokens := strings.FieldsFunc(displayName, func(r rune) bool {
+		return strings.ContainsRune(adDisplayNameDelims, r)
+	})
+	for _, token := range tokens {
+		if len(token) >= adMinSubstringLength && strings.Contains(lowered, strings.ToLower(token)) {
+			violations = append(violations, "contains a part of the display name")
+			break
+		}
+	}
+	return violations
+}
+
+func applyADComplexityPreset(restrictions *PasswordRestrictions) {
+	if restrictions.MinLength < adMinLength {
+		restrictions.MinLength = adMinLength
+		restrictions.warn(fmt.Sprintf("Parameter minLength raised to %d by adComplexity", adMinLength))
+	}
+	if restrictions.MinDigits < 1 {
+		restrictions.MinDigits = 1
+		restrictions.warn("Parameter minDigits raised to 1 by adComplexity")
+	}
+	if restrictions.MinSpecialChars < 1 {
+		restrictions.MinSpecialChars = 1
+		restrictions.warn("Parameter minSpecialChars raised to 1 by adComplexity")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"net/url"
+	"password_gen/markov_chain"
+
+	"github.com/gorilla/mux"
+)
+
+func newAdminRouter() *mux.Router {
+	router := mux.NewRouter()
+
+	router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	router.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	router.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	router.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+	router.Handle("/debug/vars", expvar.Handler())
+	router.HandleFunc("/admin/status", requireAdminToken(handleAdminStatus)).Methods("GET")
+	router.HandleFunc("/admin/wordlists", requireAdminToken(handleListWordlists)).Methods("GET")
+	router.HandleFunc("/admin/wordlists/{name}", audited("wordlist", requireAdminToken(managementAvailable(handlePutWordlist)))).Methods("PUT")
+	router.HandleFunc("/admin/wordlists/{name}", audited("wordlist", requireAdminToken(managementAvailable(handleDeleteWordlist)))).Methods("DELETE")
+	router.HandleFunc("/admin/blocklist", requireAdminToken(handleGetBlocklist)).Methods("GET")
+	router.HandleFunc("/admin/blocklist", audited("blocklist", requireAdminToken(handlePutBlocklist))).Methods("PUT")
+	router.HandleFunc("/admin/blocklist", audited("blocklist", requireAdminToken(handleDeleteBlocklist))).Methods("DELETE")
+	router.HandleFunc("/admin/maintenance", requireAdminToken(handleGetMaintenance)).Methods("GET")
+	router.HandleFunc("/admin/maintenance", audited("maintenance", requireAdminToken(handleEnableMaintenance))).Methods("PUT")
+	router.HandleFunc("/admin/maintenance", audited("maintenance", requireAdminToken(handleDisableMaintenance))).Methods("DELETE")
+	return router
+}
+
+func handleAdminRequests(addr string) {
+	fmt.Println("Admin listener serving status, wordlists, blocklist, maintenance, pprof and expvar on", addr)
+	log.Fatal(http.ListenAndServe(addr, newAdminRouter()))
+}
+
+type AdminStatus struct {
+	Models      []markov_chain.ModelInfo `json:"models"`
+	Config      Config                   `json:"config"`
+	Maintenance bool                     `json:"maintenance"`
+}
+
+func handleAdminStatus(w http.ResponseWriter, r *http.Request) {
+	status := AdminStatus{
+		Models:      []markov_chain.ModelInfo{markov_chain.DescribeModel()},
+		Config:      config,
+		Maintenance: maintenance.Load(),
+	}
+	if status.Config.AdminToken != "" {
+		status.Config.AdminToken = redacted
+	}
+	if status.Config.ClaimSecret != "" {
+		status.Config.ClaimSecret = redacted
+	}
+	if status.Config.ModelAgeWebhook != "" {
+		status.Config.ModelAgeWebhook = redacted
+	}
+	status.Config.BreachURL = redactURL(status.Config.BreachURL)
+	status.Config.AuditTarget = redactURL(status.Config.AuditTarget)
+	status.Config.BusURL = redactURL(status.Config.BusURL)
+	writeResponse(w, r, 200, status)
+}
+
+// redactURL redacts the credentials of a url, a username alone being a token
+// as often as not. Values which aren't urls, like file paths, are kept.
+func redactURL(value string) string {
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.User == nil {
+		return value
+	}
+	if _, ok := parsed.User.Password(); !ok {
+		parsed.User = url.UserPassword("", "")
+	}
+	return parsed.Redacted()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+func resolveAffixes(restrictions *PasswordRestrictions) error {
+	for _, affix := range []struct {
+		parameter string
+		value     string
+	}{
+		{"prefix", restrictions.Prefix},
+		{"suffix", restrictions.Suffix},
+	} {
+		if !validAllowedChars(affix.value) {
+			return fmt.Errorf("Parameter %s can only contain printable ASCII characters", affix.parameter)
+		}
+	}
+	if restrictions.ClassSpacing > 0 && (restrictions.Prefix != "" || restrictions.Suffix != "") {
+		return errors.New("Parameter classSpacing can't be combined with prefix or suffix")
+	}
+	return nil
+}
+
+// generated returns the bounds of the generated part of a password of the
+// given length, between the fixed prefix and suffix which repairs leave
+// alone.
+func (restrictions PasswordRestrictions) generated(length int) (int, int) {
+	return utf8.RuneCountInString(restrictions.Prefix), length - len(restrictions.Suffix)
+}
+
+// withoutSuffix are the restrictions of the password generated before the
+// suffix is attached, with the room and the characters the suffix takes.
+func (restrictions PasswordRestrictions) withoutSuffix() PasswordRestrictions {
+	suffix := restrictions.Suffix
+	if suffix == "" {
+		return restrictions
+	}
+	restrictions.Suffix = ""
+	restrictions.MaxLength -= len(suffix)
+	restrictions.MinLength = max(0, restrictions.MinLength-len(suffix))
+	restrictions.MinDigits = max(0, restrictions.MinDigits-countCharset(suffix, Digits))
+	restrictions.MinSpecialChars = max(0, restrictions.MinSpecialChars-countCharset(suffix, restrictions.countedSpecials()))
+	restrictions.MinUpperCase = max(0, restrictions.MinUpperCase-countCharset(suffix, UpperLetters))
+	restrictions.MinLowerCase = max(0, restrictions.MinLowerCase-countCharset(suffix, Letters))
+	restrictions.MinLetters = max(0, restrictions.MinLetters-countCharset(suffix, Letters+UpperLetters))
+	return restrictions
+}
+
+func affixProblems(restrictions PasswordRestrictions) []string {
+	fixed := restrictions.Prefix + restrictions.Suffix
+	if fixed == "" {
+		return nil
+	}
+	var problems []string
+	if len(fixed) >= restrictions.MaxLength {
+		problems = append(problems, fmt.Sprintf("prefix and suffix take %d characters, which leaves no room in maxLength (%d)", len(fixed), restrictions.MaxLength))
+	}
+	for _, affix := range []struct {
+		parameter string
+		value     string
+	}{
+		{"prefix", restrictions.Prefix},
+		{"suffix", restrictions.Suffix},
+	} {
+		if strings.ContainsAny(affix.value, restrictions.excluded()) {
+			problems = append(problems, fmt.Sprintf("%s %q contains characters excluded by excludeChars or excludeAmbiguous", affix.parameter, affix.value))
+		}
+		if !restrictions.allowed(affix.value) {
+			problems = append(problems, fmt.Sprintf("%s %q contains characters outside allowedChars", affix.parameter, affix.value))
+		}
+	}
+	for _, group := range groupCaps(restrictions) {
+		if group.max != nil && len(classPositions([]rune(fixed), group.class, 0, len(fixed))) > *group.max {
+			problems = append(problems, fmt.Sprintf("prefix and suffix have more characters than %s (%d) allows", group.parameter, *group.max))
+		}
+	}
+	if restrictions.MaxCharOccurrences > 0 && overusedChars(fixed, restrictions.MaxCharOccurrences) {
+		problems = append(problems, fmt.Sprintf("prefix and suffix use characters more often than maxCharOccurrences (%d) allows", restrictions.MaxCharOccurrences))
+	}
+	unfixed := restrictions
+	unfixed.Prefix, unfixed.Suffix = "", ""
+	if restrictions.NoSequences && (hasSequences(restrictions.Prefix, unfixed) || hasSequences(restrictions.Suffix, unfixed)) {
+		problems = append(problems, fmt.Sprintf("prefix or suffix contains a sequence longer than maxSequenceLength (%d)", restrictions.MaxSequenceLength))
+	}
+	if restrictions.StartsWithLetter && restrictions.Prefix != "" && !isLetter(rune(restrictions.Prefix[0])) {
+		problems = append(problems, fmt.Sprintf("startsWithLetter can't be satisfied with prefix %q", restrictions.Prefix))
+	}
+	if restrictions.EndsWithAlnum && restrictions.Suffix != "" && strings.IndexByte(Letters+UpperLetters+Digits, restrictions.Suffix[len(restrictions.Suffix)-1]) < 0 {
+		problems = append(problems, fmt.Sprintf("endsWithAlnum can't be satisfied with suffix %q", restrictions.Suffix))
+	}
+	return problems
+}
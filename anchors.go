@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"password_gen/random"
+	"strings"
+)
+
+type anchor struct {
+	parameter string
+	position  func(length int) int
+	fits      func(ch rune) bool
+	charset   string
+}
+
+func anchors(restrictions PasswordRestrictions) []anchor {
+	var anchors []anchor
+	if restrictions.StartsWithLetter {
+		anchors = append(anchors, anchor{
+			parameter: "startsWithLetter",
+			position:  func(int) int { return 0 },
+			fits:      isLetter,
+			charset:   restrictions.lower() + restrictions.upper(),
+		})
+	}
+	if restrictions.EndsWithAlnum {
+		anchors = append(anchors, anchor{
+			parameter: "endsWithAlnum",
+			position:  func(length int) int { return length - 1 },
+			fits:      func(ch rune) bool { return isLetter(ch) || isDigit(ch) },
+			charset:   restrictions.lower() + restrictions.upper() + Digits,
+		})
+	}
+	return anchors
+}
+
+func (restrictions PasswordRestrictions) limitsAnchors() bool {
+	return (restrictions.StartsWithLetter || restrictions.EndsWithAlnum) && restrictions.Mode == "" && restrictions.PassphrasePattern == "" && restrictions.Pattern == ""
+}
+
+// anchorCharacters swaps a fitting character from elsewhere in the password
+// into the anchored first and last positions, which keeps the character
+// groups intact. A password without any fitting character is left for the
+// check to reject.
+func anchorCharacters(password string, restrictions PasswordRestrictions) (string, error) {
+	anchored := []rune(password)
+	positions := map[int]bool{}
+	for _, anchor := range anchors(restrictions) {
+		positions[anchor.position(len(anchored))] = true
+	}
+	start, end := restrictions.generated(len(anchored))
+	count := 0
+	for _, anchor := range anchors(restrictions) {
+		position := anchor.position(len(anchored))
+		if position < start || position >= end || anchor.fits(anchored[position]) {
+			continue
+		}
+		var candidates, spaced []int
+		for i := start; i < end; i++ {
+			if !positions[i] && anchor.fits(anchored[i]) {
+				candidates = append(candidates, i)
+				if restrictions.ClassSpacing > 0 && swapsSpacedOut(anchored, position, i, restrictions.ClassSpacing) {
+					spaced = append(spaced, i)
+				}
+			}
+		}
+		// With classSpacing, swaps keeping the password spaced out are
+		// preferred. Without any, a digit or special character the group
+		// minimums can spare is replaced by a letter, which keeps it spaced
+		// out too, and otherwise the check rejects the password.
+		if len(spaced) > 0 {
+			candidates = spaced
+		} else if restrictions.ClassSpacing > 0 && restrictions.spares(anchored, position) {
+			letter, err := randomRune(restrictions.charset(restrictions.lower() + restrictions.upper()))
+			if err != nil {
+				return "", err
+			}
+			anchored[position] = letter
+			count++
+			continue
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+		j, err := random.Intn(len(candidates))
+		if err != nil {
+			return "", err
+		}
+		anchored[position], anchored[candidates[j]] = anchored[candidates[j]], anchored[position]
+		count++
+	}
+	if count > 0 {
+		recordRepair("anchors", count)
+	}
+	return string(anchored), nil
+}
+
+// spares reports whether the character at position of password can be
+// replaced without falling short of a group minimum.
+func (restrictions PasswordRestrictions) spares(password []rune, position int) bool {
+	for _, requirement := range groupRequirements(restrictions) {
+		if strings.ContainsRune(requirement.counts, password[position]) && countCharset(string(password), requirement.counts) <= requirement.count {
+			return false
+		}
+	}
+	return true
+}
+
+func swapsSpacedOut(password []rune, i, j, spacing int) bool {
+	swapped := append([]rune{}, password...)
+	swapped[i], swapped[j] = swapped[j], swapped[i]
+	return isSpacedOut(string(swapped), spacing)
+}
+
+func checkAnchors(password string, restrictions PasswordRestrictions) error {
+	runes := []rune(password)
+	for _, anchor := range anchors(restrictions) {
+		if position := anchor.position(len(runes)); position < 0 || !anchor.fits(runes[position]) {
+			return violation("anchors", errors.New("Generated password doesn't satisfy "+anchor.parameter+", try again"))
+		}
+	}
+	return nil
+}
+
+func anchorProblems(restrictions PasswordRestrictions) []string {
+	var problems []string
+	for _, anchor := range anchors(restrictions) {
+		if restrictions.charset(anchor.charset) == "" {
+			problems = append(problems, anchor.parameter+" can't be satisfied because allowedChars and excludeChars leave no fitting characters")
+		}
+	}
+	return problems
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	auditSchemaVersion = 1
+	auditQueueSize     = 1024
+	auditTimeout       = 5 * time.Second
+)
+
+var auditEventsDropped = expvar.NewInt("audit_events_dropped")
+
+// AuditEvent describes a request issuing credentials or changing the
+// service, for SIEM ingestion. It never contains passwords or parameter
+// values, which can carry usernames.
+type AuditEvent struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	Time          time.Time `json:"time"`
+	Type          string    `json:"type"`
+	Endpoint      string    `json:"endpoint"`
+	Method        string    `json:"method"`
+	Status        int       `json:"status"`
+	Outcome       string    `json:"outcome"`
+	Client        string    `json:"client"`
+	Parameters    []string  `json:"parameters"`
+	Count         int       `json:"count,omitempty"`
+	DurationMs    int64     `json:"durationMs"`
+}
+
+var auditEventNames = map[string]string{
+	"generation":  "Password generation",
+	"wordlist":    "Wordlist change",
+	"blocklist":   "Blocklist change",
+	"maintenance": "Maintenance mode change",
+}
+
+type auditLog struct {
+	events chan []byte
+	format string
+}
+
+// audit is nil unless auditSink is configured.
+var audit *auditLog
+
+func newAuditLog(c Config) (*auditLog, error) {
+	var sink io.Writer
+	switch c.AuditSink {
+	case "", "none":
+		return nil, nil
+	case "file":
+		file, err := os.OpenFile(c.AuditTarget, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("Audit log %s can't be opened: %w", c.AuditTarget, err)
+		}
+		sink = file
+	case "tcp":
+		sink = &tcpSink{address: c.AuditTarget}
+	case "kafka":
+		sink = &kafkaRESTSink{client: &http.Client{Timeout: auditTimeout}, url: c.AuditTarget, format: c.AuditFormat}
+	default:
+		return nil, fmt.Errorf("Audit sink %q is unknown", c.AuditSink)
+	}
+	log := &auditLog{events: make(chan []byte, auditQueueSize), format: c.AuditFormat}
+	go func() {
+		for event := range log.events {
+			if _, err := sink.Write(event); err != nil {
+				auditEventsDropped.Add(1)
+			}
+		}
+	}()
+	return log, nil
+}
+
+// record queues the event without blocking the request, events which don't
+// fit in the queue are dropped and counted.
+func (l *auditLog) record(event AuditEvent) {
+	var line []byte
+	if l.format == "cef" {
+		line = []byte(event.cef() + "\n")
+	} else {
+		line, _ = json.Marshal(event)
+		line = append(line, '\n')
+	}
+	select {
+	case l.events <- line:
+	default:
+		auditEventsDropped.Add(1)
+	}
+}
+
+func cefHeader(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "|", `\|`).Replace(value)
+}
+
+func cefExtension(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "=", `\=`, "\n", `\n`, "\r", `\r`).Replace(value)
+}
+
+// cef formats the event in the ArcSight Common Event Format, with the schema
+// version and the parameter names in custom string fields.
+func (event AuditEvent) cef() string {
+	severity := 3
+	if event.Outcome != "success" {
+		severity = 5
+	}
+	extension := []string{
+		"rt=" + strconv.FormatInt(event.Time.UnixMilli(), 10),
+		"src=" + cefExtension(event.Client),
+		"request=" + cefExtension(event.Endpoint),
+		"requestMethod=" + cefExtension(event.Method),
+		"outcome=" + event.Outcome,
+		"cn1Label=status",
+		"cn1=" + strconv.Itoa(event.Status),
+		"cn2Label=durationMs",
+		"cn2=" + strconv.FormatInt(event.DurationMs, 10),
+		"cs1Label=schemaVersion",
+		"cs1=" + strconv.Itoa(event.SchemaVersion),
+		"cs2Label=parameters",
+		"cs2=" + cefExtension(strings.Join(event.Parameters, ",")),
+	}
+	if event.Count > 0 {
+		extension = append(extension, "cnt="+strconv.Itoa(event.Count))
+	}
+	return fmt.Sprintf("CEF:0|password_gen|password_gen|%d|%s|%s|%d|%s",
+		auditSchemaVersion, cefHeader(event.Type), cefHeader(auditEventNames[event.Type]), severity, strings.Join(extension, " "))
+}
+
+// tcpSink writes events to a collector over TCP, reconnecting after
+// failures. Events written while the collector is unreachable are lost.
+type tcpSink struct {
+	address string
+	conn    net.Conn
+}
+
+func (s *tcpSink) Write(event []byte) (int, error) {
+	if s.conn == nil {
+		conn, err := net.DialTimeout("tcp", s.address, auditTimeout)
+		if err != nil {
+			return 0, err
+		}
+		s.conn = conn
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(auditTimeout))
+	n, err := s.conn.Write(event)
+	if err != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	return n, err
+}
+
+// kafkaRESTSink produces events to a Kafka topic through the Confluent REST
+// proxy, auditTarget being the topic url, e.g.
+// http://kafka-rest:8082/topics/password-gen-audit.
+type kafkaRESTSink struct {
+	client *http.Client
+	url    string
+	format string
+}
+
+func (s *kafkaRESTSink) Write(event []byte) (int, error) {
+	value := json.RawMessage(bytes.TrimSpace(event))
+	if s.format == "cef" {
+		value, _ = json.Marshal(string(bytes.TrimSpace(event)))
+	}
+	body, _ := json.Marshal(map[string]any{"records": []map[string]any{{"value": value}}})
+	response, err := s.client.Post(s.url, kafkaJSONV2, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, response.Body)
+	if response.StatusCode >= 300 {
+		return 0, errors.New("REST proxy responded with " + response.Status)
+	}
+	return len(event), nil
+}
+
+type auditWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *auditWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = 200
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *auditWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *auditWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response can't be hijacked")
+	}
+	w.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// audited records an audit event of eventType for every request once it is
+// answered.
+func audited(eventType string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if audit == nil {
+			next(w, r)
+			return
+		}
+		started := time.Now()
+		writer := &auditWriter{ResponseWriter: w}
+		next(writer, r)
+
+		query := r.URL.Query()
+		parameters := make([]string, 0, len(query))
+		for key := range query {
+			parameters = append(parameters, key)
+		}
+		sort.Strings(parameters)
+		count, _ := strconv.Atoi(query.Get("count"))
+		outcome := "success"
+		if writer.status >= 400 {
+			outcome = "failure"
+		}
+		audit.record(AuditEvent{
+			SchemaVersion: auditSchemaVersion,
+			Time:          started.UTC(),
+			Type:          eventType,
+			Endpoint:      r.URL.Path,
+			Method:        r.Method,
+			Status:        writer.status,
+			Outcome:       outcome,
+			Client:        clientIP(r),
+			Parameters:    parameters,
+			Count:         count,
+			DurationMs:    time.Since(started).Milliseconds(),
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import "password_gen/random"
+
+func resolveBalanced(restrictions *PasswordRestrictions) {
+	if !restrictions.Balanced {
+		return
+	}
+	reason := ""
+	switch {
+	case restrictions.UserReadable:
+		reason = "userReadable is set"
+	case restrictions.Mode != "":
+		reason = "mode is set"
+	case restrictions.PassphrasePattern != "":
+		reason = "passphrasePattern is set"
+	case restrictions.Pattern != "":
+		reason = "pattern is set"
+	}
+	if reason != "" {
+		restrictions.warn("Parameter balanced ignored because " + reason)
+		restrictions.Balanced = false
+	}
+}
+
+// characterClasses are the permitted letters of both cases, digits and
+// special characters, characters of unicodeRanges joining the letters or
+// the special characters. Classes without permitted characters are empty.
+func (restrictions PasswordRestrictions) characterClasses() []string {
+	letters := restrictions.lower() + restrictions.upper()
+	specials := restrictions.specials()
+	for _, ch := range restrictions.unicodeChars() {
+		if characterClass(ch) == SpecialChars {
+			specials += string(ch)
+		} else {
+			letters += string(ch)
+		}
+	}
+	return []string{restrictions.charset(letters), restrictions.charset(Digits), restrictions.charset(specials)}
+}
+
+// balancedClasses are the characterClasses which aren't empty.
+func (restrictions PasswordRestrictions) balancedClasses() []string {
+	var classes []string
+	for _, class := range restrictions.characterClasses() {
+		if class != "" {
+			classes = append(classes, class)
+		}
+	}
+	return classes
+}
+
+// generateBalancedPassword draws about as many characters from every class
+// of balancedClasses, the classes getting one more character picked at
+// random when length isn't divisible by their number.
+func generateBalancedPassword(length int, restrictions PasswordRestrictions) (string, error) {
+	classes := restrictions.balancedClasses()
+	if len(classes) == 0 || length <= 0 {
+		return "", nil
+	}
+	counts := make([]int, len(classes))
+	for i := range counts {
+		counts[i] = length / len(classes)
+	}
+	extra := make([]int, len(classes))
+	for i := range extra {
+		extra[i] = i
+	}
+	for i := 0; i < length%len(classes); i++ {
+		j, err := random.Intn(len(extra))
+		if err != nil {
+			return "", err
+		}
+		counts[extra[j]]++
+		extra = append(extra[:j], extra[j+1:]...)
+	}
+
+	password := make([]rune, 0, length)
+	for i, class := range classes {
+		for n := 0; n < counts[i]; n++ {
+			ch, err := randomRune(class)
+			if err != nil {
+				return "", err
+			}
+			password, err = insertAtRandom(password, ch)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+	return string(password), nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	defaultBatchCount = 10
+	ndjsonContentType = "application/x-ndjson"
+)
+
+type BatchResponse struct {
+	Error         string                `json:"error"`
+	Passwords     []string              `json:"passwords"`
+	Warnings      []string              `json:"warnings,omitempty"`
+	PolicyApplied *PasswordRestrictions `json:"policyApplied,omitempty"`
+	Compositions  []Composition         `json:"compositions,omitempty"`
+}
+
+func parseBatchCount(query url.Values) (int, error) {
+	if query.Get("count") == "" {
+		return defaultBatchCount, nil
+	}
+	count, err := strconv.Atoi(query.Get("count"))
+	if err != nil || count < 1 {
+		return 0, errors.New("Parameter count has to be a positive number")
+	}
+	if count > config.MaxBatchCount {
+		return 0, errors.New("Parameter count can't be larger than " + strconv.Itoa(config.MaxBatchCount))
+	}
+	return count, nil
+}
+
+func generateBatch(count int, restrictions PasswordRestrictions, issued *issuedPasswords, emit func(password string) error) error {
+	for i := 0; i < count; i++ {
+		password, err := generateUnissued(restrictions, issued)
+		if err != nil {
+			return err
+		}
+		if err = emit(password); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func streamBatch(w http.ResponseWriter, count int, restrictions PasswordRestrictions, issued *issuedPasswords, composition bool) {
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.WriteHeader(200)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	flusher, _ := w.(http.Flusher)
+
+	err := generateBatch(count, restrictions, issued, func(password string) error {
+		response := Response{Error: "", Password: password, Warnings: restrictions.warnings, PolicyApplied: &restrictions}
+		if composition {
+			described := describeComposition(password)
+			response.Composition = &described
+		}
+		if err := encoder.Encode(response); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		encoder.Encode(errorResponse(err))
+	}
+}
+
+func handlePasswordGenBatch(w http.ResponseWriter, r *http.Request) {
+	query, err := requestQuery(r)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	count, err := parseBatchCount(query)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	restrictions, err := parseRestrictions(query)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	composition, err := parseComposition(query)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	var issued *issuedPasswords
+	if r.Method == http.MethodPost {
+		issued, err = parseIssued(r)
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+	}
+
+	if accepts(r, ndjsonContentType) {
+		streamBatch(w, count, restrictions, issued, composition)
+		return
+	}
+
+	passwords := make([]string, 0, count)
+	var compositions []Composition
+	err = generateBatch(count, restrictions, issued, func(password string) error {
+		passwords = append(passwords, password)
+		if composition {
+			compositions = append(compositions, describeComposition(password))
+		}
+		return nil
+	})
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	writeResponse(w, r, 200, BatchResponse{Error: "", Passwords: passwords, Warnings: restrictions.warnings, PolicyApplied: &restrictions, Compositions: compositions})
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"password_gen/dictionary"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	minBlockedTermLen      = 3
+	maxBlockedTermLen      = 64
+	blocklistCheckInterval = 10 * time.Second
+)
+
+// blocklist holds the organization's blocked terms, lowercased, which no
+// generated password may contain and scoring reports. It's empty unless
+// blocklistFile is configured.
+var blocklist atomic.Pointer[[]string]
+
+// blocklistMutex serializes reloads with the admin changes to the file.
+var blocklistMutex sync.Mutex
+
+var blocklistModified time.Time
+
+type BlocklistResponse struct {
+	Error string   `json:"error"`
+	Terms []string `json:"terms"`
+}
+
+var errNoBlocklistFile = statusError{status: http.StatusNotFound, message: "Blocklist isn't configured, set blocklistFile"}
+
+func parseBlocklist(data string) []string {
+	seen := map[string]bool{}
+	terms := []string{}
+	for _, line := range strings.Split(data, "\n") {
+		term := strings.ToLower(strings.TrimSpace(line))
+		if term == "" || strings.HasPrefix(term, "#") || seen[term] {
+			continue
+		}
+		seen[term] = true
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+func isUnprintable(r rune) bool {
+	return !unicode.IsPrint(r)
+}
+
+func validateBlocklist(terms []string) error {
+	var invalid []string
+	for _, term := range terms {
+		if length := utf8.RuneCountInString(term); length < minBlockedTermLen || length > maxBlockedTermLen || strings.IndexFunc(term, isUnprintable) >= 0 {
+			invalid = append(invalid, term)
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("Blocklist is invalid: %d terms aren't %d to %d printable characters: %s", len(invalid), minBlockedTermLen, maxBlockedTermLen, sample(invalid))
+	}
+	return nil
+}
+
+func blockedTermsList() []string {
+	if terms := blocklist.Load(); terms != nil {
+		return *terms
+	}
+	return []string{}
+}
+
+// loadBlocklist reads blocklistFile if it changed since it was last read, a
+// missing file is an empty blocklist.
+func loadBlocklist() error {
+	blocklistMutex.Lock()
+	defer blocklistMutex.Unlock()
+	stat, err := os.Stat(config.BlocklistFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		blocklist.Store(&[]string{})
+		blocklistModified = time.Time{}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Blocklist %s can't be read: %w", config.BlocklistFile, err)
+	}
+	if stat.ModTime().Equal(blocklistModified) && blocklist.Load() != nil {
+		return nil
+	}
+	data, err := os.ReadFile(config.BlocklistFile)
+	if err != nil {
+		return fmt.Errorf("Blocklist %s can't be read: %w", config.BlocklistFile, err)
+	}
+	terms := parseBlocklist(string(data))
+	if err := validateBlocklist(terms); err != nil {
+		return err
+	}
+	blocklist.Store(&terms)
+	blocklistModified = stat.ModTime()
+	return nil
+}
+
+// watchBlocklist reloads blocklistFile when it changes or on SIGHUP, keeping
+// the previous terms when the file is invalid.
+func watchBlocklist() error {
+	if config.BlocklistFile == "" {
+		return nil
+	}
+	if err := loadBlocklist(); err != nil {
+		return err
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		ticker := time.NewTicker(blocklistCheckInterval)
+		for {
+			select {
+			case <-signals:
+			case <-ticker.C:
+			}
+			if err := loadBlocklist(); err != nil {
+				log.Printf("blocklist: %v, keeping %d terms", err, len(blockedTermsList()))
+			}
+		}
+	}()
+	return nil
+}
+
+// blockedTerms are the blocked terms password contains, ignoring case and
+// leet substitutes.
+func blockedTerms(password string) []string {
+	terms := blockedTermsList()
+	if len(terms) == 0 {
+		return nil
+	}
+	var found []string
+	for _, term := range terms {
+		if dictionary.ContainsLeet(password, term) {
+			found = append(found, term)
+		}
+	}
+	return found
+}
+
+func checkBlocklist(password string) error {
+	if len(blockedTerms(password)) > 0 {
+		return violation("blocklist", errors.New("Generated password contains a blocked term, try again"))
+	}
+	return nil
+}
+
+func storeBlocklist(terms []string) error {
+	blocklistMutex.Lock()
+	defer blocklistMutex.Unlock()
+	temp, err := os.CreateTemp(filepath.Dir(config.BlocklistFile), ".blocklist-*")
+	if err != nil {
+		return statusError{status: http.StatusInternalServerError, message: "Blocklist can't be stored"}
+	}
+	defer os.Remove(temp.Name())
+	data := ""
+	if len(terms) > 0 {
+		data = strings.Join(terms, "\n") + "\n"
+	}
+	_, err = temp.WriteString(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), config.BlocklistFile)
+	}
+	if err != nil {
+		return statusError{status: http.StatusInternalServerError, message: "Blocklist can't be stored"}
+	}
+	if stat, err := os.Stat(config.BlocklistFile); err == nil {
+		blocklistModified = stat.ModTime()
+	}
+	blocklist.Store(&terms)
+	return nil
+}
+
+func handleGetBlocklist(w http.ResponseWriter, r *http.Request) {
+	if config.BlocklistFile == "" {
+		handleError(w, r, errNoBlocklistFile)
+		return
+	}
+	writeResponse(w, r, 200, BlocklistResponse{Terms: blockedTermsList()})
+}
+
+func handlePutBlocklist(w http.ResponseWriter, r *http.Request) {
+	if config.BlocklistFile == "" {
+		handleError(w, r, errNoBlocklistFile)
+		return
+	}
+	if config.MaxBodyBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, config.MaxBodyBytes)
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		handleError(w, r, bodyError(err, "Body can't be read"))
+		return
+	}
+	terms := parseBlocklist(string(body))
+	if err := validateBlocklist(terms); err != nil {
+		handleError(w, r, err)
+		return
+	}
+	if err := storeBlocklist(terms); err != nil {
+		handleError(w, r, err)
+		return
+	}
+	writeResponse(w, r, 200, BlocklistResponse{Terms: terms})
+}
+
+func handleDeleteBlocklist(w http.ResponseWriter, r *http.Request) {
+	if config.BlocklistFile == "" {
+		handleError(w, r, errNoBlocklistFile)
+		return
+	}
+	if err := storeBlocklist([]string{}); err != nil {
+		handleError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
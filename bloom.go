@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+const bloomMagic = "PGBLOOM1"
+
+// bloomFilter is a bloom filter over the sha1 hashes of passwords, so it
+// can be built from the sha1 dumps of Have I Been Pwned. The two halves of
+// the hash are combined into the k bit positions.
+type bloomFilter struct {
+	bits []byte
+	m    uint64
+	k    uint32
+}
+
+func newBloomFilter(n uint64, falsePositiveRate float64) *bloomFilter {
+	m := uint64(math.Ceil(-float64(max(n, 1)) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	k := uint32(max(1, math.Round(float64(m)/float64(max(n, 1))*math.Ln2)))
+	return &bloomFilter{bits: make([]byte, (m+7)/8), m: m, k: k}
+}
+
+func (f *bloomFilter) positions(hash [sha1.Size]byte, visit func(bit uint64) bool) bool {
+	h1 := binary.BigEndian.Uint64(hash[0:8])
+	h2 := binary.BigEndian.Uint64(hash[8:16]) | 1
+	for i := uint32(0); i < f.k; i++ {
+		if !visit((h1 + uint64(i)*h2) % f.m) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *bloomFilter) add(hash [sha1.Size]byte) {
+	f.positions(hash, func(bit uint64) bool {
+		f.bits[bit/8] |= 1 << (bit % 8)
+		return true
+	})
+}
+
+func (f *bloomFilter) contains(hash [sha1.Size]byte) bool {
+	return f.positions(hash, func(bit uint64) bool {
+		return f.bits[bit/8]&(1<<(bit%8)) != 0
+	})
+}
+
+func (f *bloomFilter) writeTo(w io.Writer) error {
+	header := make([]byte, len(bloomMagic)+12)
+	copy(header, bloomMagic)
+	binary.BigEndian.PutUint64(header[len(bloomMagic):], f.m)
+	binary.BigEndian.PutUint32(header[len(bloomMagic)+8:], f.k)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(f.bits)
+	return err
+}
+
+func readBloomFilter(path string) (*bloomFilter, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	headerSize := len(bloomMagic) + 12
+	if len(data) < headerSize || string(data[:len(bloomMagic)]) != bloomMagic {
+		return nil, errors.New("isn't a bloom filter built by breach-filter")
+	}
+	f := &bloomFilter{
+		m: binary.BigEndian.Uint64(data[len(bloomMagic):]),
+		k: binary.BigEndian.Uint32(data[len(bloomMagic)+8:]),
+	}
+	f.bits = data[headerSize:]
+	if f.m == 0 || f.k == 0 || uint64(len(f.bits)) != (f.m+7)/8 {
+		return nil, errors.New("bloom filter is truncated or corrupt")
+	}
+	return f, nil
+}
+
+// breachHash reads a line of a breach list, either a hex sha1 hash with an
+// optional :count suffix, as in the Have I Been Pwned dumps, or a plain
+// password.
+func breachHash(line string) ([sha1.Size]byte, bool) {
+	line = strings.TrimRight(line, "\r")
+	if line == "" {
+		return [sha1.Size]byte{}, false
+	}
+	hexHash, _, _ := strings.Cut(line, ":")
+	var hash [sha1.Size]byte
+	if decoded, err := hex.DecodeString(hexHash); err == nil && len(decoded) == sha1.Size {
+		copy(hash[:], decoded)
+		return hash, true
+	}
+	return sha1.Sum([]byte(line)), true
+}
+
+func buildBloomFilter(r io.Reader, n uint64, falsePositiveRate float64) (*bloomFilter, uint64, error) {
+	f := newBloomFilter(n, falsePositiveRate)
+	scanner := bufio.NewScanner(r)
+	added := uint64(0)
+	for scanner.Scan() {
+		if hash, ok := breachHash(scanner.Text()); ok {
+			f.add(hash)
+			added++
+		}
+	}
+	return f, added, scanner.Err()
+}
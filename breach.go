@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBreachURL = "https://api.pwnedpasswords.com"
+	breachTimeout    = 5 * time.Second
+)
+
+var errBreachCheck = statusError{status: http.StatusServiceUnavailable, message: "Breach check is unavailable, try again later"}
+
+// BreachChecker tells whether a password is known from data breaches.
+type BreachChecker interface {
+	Breached(password string) (bool, error)
+}
+
+var breachChecker BreachChecker = noopBreachChecker{}
+
+func newBreachChecker(c Config) (BreachChecker, error) {
+	switch c.BreachProvider {
+	case "", "none":
+		return noopBreachChecker{}, nil
+	case "hibp":
+		url := c.BreachURL
+		if url == "" {
+			url = defaultBreachURL
+		}
+		return hibpBreachChecker{client: &http.Client{Timeout: breachTimeout}, url: strings.TrimSuffix(url, "/")}, nil
+	case "bloom":
+		filter, err := readBloomFilter(c.BreachFilter)
+		if err != nil {
+			return nil, fmt.Errorf("Breach filter %s can't be read: %w", c.BreachFilter, err)
+		}
+		return bloomBreachChecker{filter: filter}, nil
+	}
+	return nil, fmt.Errorf("Breach provider %q is unknown", c.BreachProvider)
+}
+
+type noopBreachChecker struct{}
+
+func (noopBreachChecker) Breached(string) (bool, error) {
+	return false, nil
+}
+
+// hibpBreachChecker queries the Have I Been Pwned range api, which only
+// gets the first 5 hex characters of the sha1 hash of the password.
+type hibpBreachChecker struct {
+	client *http.Client
+	url    string
+}
+
+func (c hibpBreachChecker) Breached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	request, err := http.NewRequest(http.MethodGet, c.url+"/range/"+hash[:5], nil)
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Add-Padding", "true")
+	request.Header.Set("User-Agent", "password_gen")
+	response, err := c.client.Do(request)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("range api responded with %s", response.Status)
+	}
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		suffix, count, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if suffix == hash[5:] {
+			return count != "0", nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// bloomBreachChecker looks passwords up in a local bloom filter built with
+// the breach-filter command, for air-gapped deployments. It has false
+// positives at the rate the filter was built for, but no false negatives.
+type bloomBreachChecker struct {
+	filter *bloomFilter
+}
+
+func (c bloomBreachChecker) Breached(password string) (bool, error) {
+	return c.filter.contains(sha1.Sum([]byte(password))), nil
+}
+
+func checkBreached(password string) error {
+	breached, err := breachChecker.Breached(password)
+	if err != nil {
+		return errBreachCheck
+	}
+	if breached {
+		return violation("breached", errors.New("Generated password is known from data breaches, try again"))
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	busRetryInterval = 5 * time.Second
+	busPollTimeout   = 30 * time.Second
+	// busMaxInFlight is how many NATS messages are answered at a time. The
+	// next message is only read once one of them is done.
+	busMaxInFlight = 64
+	// natsMaxPayload is the max_payload of NATS servers which don't announce
+	// theirs, the default of nats-server.
+	natsMaxPayload = 1 << 20
+
+	kafkaV2     = "application/vnd.kafka.v2+json"
+	kafkaJSONV2 = "application/vnd.kafka.json.v2+json"
+)
+
+// runWorker consumes generation requests from busSubject and replies with
+// the same payload as the WebSocket endpoint, until it's interrupted.
+func runWorker(ctx context.Context) error {
+	if config.BusTransport == "" || config.BusTransport == "none" {
+		return errors.New("The worker needs busTransport to be configured")
+	}
+	if problems := config.servingProblems(); len(problems) > 0 {
+		return fmt.Errorf("Can't start the worker: %s", strings.Join(problems, "; "))
+	}
+	var err error
+	if audit, err = newAuditLog(config); err != nil {
+		return err
+	}
+	if err = watchBlocklist(); err != nil {
+		return err
+	}
+	warnModelUnavailable()
+	scheduleModelAgeChecks()
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	consume := consumeNATS
+	if config.BusTransport == "kafka" {
+		consume = consumeKafka
+	}
+	log.Printf("worker: consuming %s from %s", config.BusSubject, config.BusTransport)
+	for {
+		err := consume(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		log.Printf("worker: %v, reconnecting in %s", err, busRetryInterval)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(busRetryInterval):
+		}
+	}
+}
+
+// handleBusMessage answers a request message and records it as a generation
+// audit event.
+func handleBusMessage(subject string, message []byte) []byte {
+	started := time.Now()
+	response := handleWebSocketMessage(message)
+	reply, _ := json.Marshal(response)
+	if audit != nil {
+		var fields map[string]any
+		json.Unmarshal(message, &fields)
+		parameters := make([]string, 0, len(fields))
+		for key := range fields {
+			parameters = append(parameters, key)
+		}
+		sort.Strings(parameters)
+		outcome := "success"
+		if response.Error != "" {
+			outcome = "failure"
+		}
+		audit.record(AuditEvent{
+			SchemaVersion: auditSchemaVersion,
+			Time:          started.UTC(),
+			Type:          "generation",
+			Endpoint:      subject,
+			Method:        strings.ToUpper(config.BusTransport),
+			Outcome:       outcome,
+			Client:        config.BusTransport,
+			Parameters:    parameters,
+			DurationMs:    time.Since(started).Milliseconds(),
+		})
+	}
+	return reply
+}
+
+// consumeNATS speaks the NATS client protocol: it subscribes to busSubject
+// in the busGroup queue group, so that several workers share the requests,
+// and publishes every reply to the reply subject of its request.
+func consumeNATS(ctx context.Context) error {
+	address, err := url.Parse(config.BusURL)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("tcp", address.Host, busRetryInterval)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	defer context.AfterFunc(ctx, func() { conn.Close() })()
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	info, found := strings.CutPrefix(line, "INFO ")
+	if !found {
+		return fmt.Errorf("NATS server greeted with %q", strings.TrimSpace(line))
+	}
+	server := struct {
+		MaxPayload int `json:"max_payload"`
+	}{MaxPayload: natsMaxPayload}
+	if err = json.Unmarshal([]byte(info), &server); err != nil || server.MaxPayload <= 0 {
+		server.MaxPayload = natsMaxPayload
+	}
+	options := map[string]any{"verbose": false, "pedantic": false, "name": "password_gen"}
+	if user := address.User; user != nil {
+		if password, ok := user.Password(); ok {
+			options["user"], options["pass"] = user.Username(), password
+		} else {
+			options["auth_token"] = user.Username()
+		}
+	}
+	connect, _ := json.Marshal(options)
+	var mutex sync.Mutex
+	write := func(command string, payload []byte) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(busRetryInterval))
+		if _, err := io.WriteString(conn, command+"\r\n"); err != nil {
+			return err
+		}
+		if payload == nil {
+			return nil
+		}
+		_, err := conn.Write(append(payload, '\r', '\n'))
+		return err
+	}
+	if err = write(fmt.Sprintf("CONNECT %s\r\nSUB %s %s 1", connect, config.BusSubject, config.BusGroup), nil); err != nil {
+		return err
+	}
+	inFlight := make(chan struct{}, busMaxInFlight)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "PING":
+			err = write("PONG", nil)
+		case "-ERR":
+			return errors.New("NATS server responded with " + strings.TrimSpace(line))
+		case "MSG":
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			if len(fields) != 4 && len(fields) != 5 {
+				return fmt.Errorf("NATS server sent a malformed message %q", strings.TrimSpace(line))
+			}
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil || size < 0 || size > server.MaxPayload {
+				return fmt.Errorf("NATS server sent a malformed message %q", strings.TrimSpace(line))
+			}
+			payload := make([]byte, size+2)
+			if _, err = io.ReadFull(reader, payload); err != nil {
+				return err
+			}
+			if len(fields) == 4 {
+				log.Printf("worker: request on %s without a reply subject dropped", fields[1])
+				continue
+			}
+			subject, replyTo := fields[1], fields[3]
+			select {
+			case inFlight <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			go func() {
+				defer func() { <-inFlight }()
+				reply := handleBusMessage(subject, payload[:size])
+				if err := write(fmt.Sprintf("PUB %s %d", replyTo, len(reply)), reply); err != nil {
+					conn.Close()
+				}
+			}()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+type kafkaRecord struct {
+	Key   json.RawMessage `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// kafkaConsumer is a consumer instance of the Confluent REST proxy at
+// busURL, in the busGroup consumer group.
+type kafkaConsumer struct {
+	client  *http.Client
+	baseURI string
+}
+
+func (c *kafkaConsumer) request(ctx context.Context, method, url string, body any, contentType string, result any) error {
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", contentType)
+	request.Header.Set("Accept", kafkaV2)
+	if method == http.MethodGet {
+		request.Header.Set("Accept", kafkaJSONV2)
+	}
+	response, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		io.Copy(io.Discard, response.Body)
+		return fmt.Errorf("REST proxy responded to %s %s with %s", method, url, response.Status)
+	}
+	if result == nil {
+		io.Copy(io.Discard, response.Body)
+		return nil
+	}
+	return json.NewDecoder(response.Body).Decode(result)
+}
+
+// consumeKafka polls busSubject through the Confluent REST proxy and
+// produces every reply to busReplyTopic with the key of its request, which
+// callers correlate replies with. Offsets are committed automatically.
+func consumeKafka(ctx context.Context) error {
+	base := strings.TrimSuffix(config.BusURL, "/")
+	hostname, _ := os.Hostname()
+	consumer := &kafkaConsumer{client: &http.Client{Timeout: busPollTimeout + busRetryInterval}}
+	var instance struct {
+		BaseURI string `json:"base_uri"`
+	}
+	err := consumer.request(ctx, http.MethodPost, base+"/consumers/"+url.PathEscape(config.BusGroup), map[string]string{
+		"name":              fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		"format":            "json",
+		"auto.offset.reset": "latest",
+	}, kafkaV2, &instance)
+	if err != nil {
+		return err
+	}
+	consumer.baseURI = instance.BaseURI
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), busRetryInterval)
+		defer cancel()
+		consumer.request(ctx, http.MethodDelete, consumer.baseURI, nil, kafkaV2, nil)
+	}()
+	err = consumer.request(ctx, http.MethodPost, consumer.baseURI+"/subscription", map[string][]string{
+		"topics": {config.BusSubject},
+	}, kafkaV2, nil)
+	if err != nil {
+		return err
+	}
+
+	replies := base + "/topics/" + url.PathEscape(config.BusReplyTopic)
+	for {
+		var records []kafkaRecord
+		err = consumer.request(ctx, http.MethodGet, fmt.Sprintf("%s/records?timeout=%d", consumer.baseURI, busPollTimeout.Milliseconds()), nil, kafkaV2, &records)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			reply := json.RawMessage(handleBusMessage(config.BusSubject, record.Value))
+			err = consumer.request(ctx, http.MethodPost, replies, map[string]any{
+				"records": []map[string]any{{"key": record.Key, "value": reply}},
+			}, kafkaJSONV2, nil)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
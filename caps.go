@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"password_gen/random"
+	"strings"
+	"unicode/utf8"
+)
+
+type groupCap struct {
+	parameter string
+	max       *int
+	class     string
+}
+
+func groupCaps(restrictions PasswordRestrictions) []groupCap {
+	return []groupCap{
+		{parameter: "maxDigits", max: restrictions.MaxDigits, class: Digits},
+		{parameter: "maxSpecialChars", max: restrictions.MaxSpecialChars, class: SpecialChars},
+	}
+}
+
+func (restrictions PasswordRestrictions) limitsGroups() bool {
+	return (restrictions.MaxDigits != nil || restrictions.MaxSpecialChars != nil) && restrictions.Mode == "" && restrictions.PassphrasePattern == "" && restrictions.Pattern == ""
+}
+
+// classPositions are the positions between from and to of characters whose
+// class is class, anything but letters, digits and spaces counting as
+// special.
+func classPositions(password []rune, class string, from, to int) []int {
+	var positions []int
+	for i := from; i < to; i++ {
+		if password[i] != ' ' && characterClass(password[i]) == class {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+// capCharacterGroups swaps digits and special characters over maxDigits and
+// maxSpecialChars, picked at random, for letters.
+func capCharacterGroups(password string, restrictions PasswordRestrictions) (string, error) {
+	capped := []rune(password)
+	start, end := restrictions.generated(len(capped))
+	fixed := []rune(restrictions.Prefix + restrictions.Suffix)
+	letters := restrictions.charset(restrictions.lower() + restrictions.upper())
+	count := 0
+	for _, group := range groupCaps(restrictions) {
+		if group.max == nil {
+			continue
+		}
+		positions := classPositions(capped, group.class, start, end)
+		for len(positions) > *group.max-len(classPositions(fixed, group.class, 0, len(fixed))) && letters != "" {
+			j, err := random.Intn(len(positions))
+			if err != nil {
+				return "", err
+			}
+			ch, err := randomRune(letters)
+			if err != nil {
+				return "", err
+			}
+			capped[positions[j]] = ch
+			positions = append(positions[:j], positions[j+1:]...)
+			count++
+		}
+	}
+	if count > 0 {
+		recordRepair("groupCaps", count)
+	}
+	return string(capped), nil
+}
+
+func checkGroupCaps(password string, restrictions PasswordRestrictions) error {
+	for _, group := range groupCaps(restrictions) {
+		if group.max != nil && len(classPositions([]rune(password), group.class, 0, utf8.RuneCountInString(password))) > *group.max {
+			return violation(group.parameter, fmt.Errorf("Generated password has more characters than %s (%d) allows, try again", group.parameter, *group.max))
+		}
+	}
+	return nil
+}
+
+func groupCapProblems(restrictions PasswordRestrictions) []string {
+	var problems []string
+	for _, group := range groupCaps(restrictions) {
+		if group.max == nil {
+			continue
+		}
+		minimum := strings.Replace(group.parameter, "max", "min", 1)
+		count := restrictions.MinDigits
+		if group.class == SpecialChars {
+			count = restrictions.MinSpecialChars
+		}
+		if *group.max < 0 {
+			problems = append(problems, fmt.Sprintf("%s (%d) can't be negative", group.parameter, *group.max))
+		} else if count > *group.max {
+			problems = append(problems, fmt.Sprintf("%s (%d) is larger than %s (%d)", minimum, count, group.parameter, *group.max))
+		}
+	}
+	if restrictions.limitsGroups() && restrictions.charset(Letters+UpperLetters) == "" {
+		problems = append(problems, "maxDigits and maxSpecialChars need letters left by allowedChars and excludeChars to replace other characters with")
+	}
+	return problems
+}
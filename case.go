@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"password_gen/random"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const defaultRandomizeCase = 0.5
+
+var caseModes = []string{"upper", "lower", "mixed", "random"}
+
+// resolveCaseMode validates caseMode and folds the allUpperCase and
+// allLowerCase flags into it, keeping them set for upper and lower.
+func resolveCaseMode(restrictions *PasswordRestrictions, query url.Values) error {
+	if restrictions.AllUpperCase && restrictions.AllLowerCase {
+		return errors.New("Parameters allUpperCase and allLowerCase can't be combined, use caseMode instead")
+	}
+	legacy := ""
+	if restrictions.AllUpperCase {
+		legacy = "upper"
+	} else if restrictions.AllLowerCase {
+		legacy = "lower"
+	}
+	if restrictions.CaseMode == "" {
+		restrictions.CaseMode = legacy
+	} else if legacy != "" && legacy != restrictions.CaseMode {
+		return fmt.Errorf("Parameter caseMode (%s) conflicts with allUpperCase or allLowerCase", restrictions.CaseMode)
+	}
+	if restrictions.CaseMode != "" && !slices.Contains(caseModes, restrictions.CaseMode) {
+		return fmt.Errorf("Parameter caseMode has to be one of %s, not %q", strings.Join(caseModes, ", "), restrictions.CaseMode)
+	}
+	restrictions.AllUpperCase = restrictions.CaseMode == "upper"
+	restrictions.AllLowerCase = restrictions.CaseMode == "lower"
+
+	if restrictions.CaseMode != "random" {
+		if query.Has("randomizeCase") {
+			restrictions.warn("Parameter randomizeCase ignored because caseMode isn't random")
+			restrictions.RandomizeCase = 0
+		}
+		return nil
+	}
+	if !query.Has("randomizeCase") {
+		restrictions.RandomizeCase = defaultRandomizeCase
+	}
+	if restrictions.RandomizeCase <= 0 || restrictions.RandomizeCase > 1 {
+		return fmt.Errorf("Parameter randomizeCase has to be larger than 0 and at most 1, not %g", restrictions.RandomizeCase)
+	}
+	return nil
+}
+
+// randomizeCase flips the case of the given fraction of letters, picked at
+// random.
+func randomizeCase(password string, fraction float64) (string, error) {
+	runes := []rune(password)
+	var letters []int
+	for i, r := range runes {
+		if unicode.IsLetter(r) {
+			letters = append(letters, i)
+		}
+	}
+	flips := int(fraction*float64(len(letters)) + 0.5)
+	for i := 0; i < flips; i++ {
+		j, err := random.Intn(len(letters))
+		if err != nil {
+			return "", err
+		}
+		index := letters[j]
+		letters = append(letters[:j], letters[j+1:]...)
+		if unicode.IsUpper(runes[index]) {
+			runes[index] = unicode.ToLower(runes[index])
+		} else {
+			runes[index] = unicode.ToUpper(runes[index])
+		}
+	}
+	return string(runes), nil
+}
+
+// caseProblems reports case modes allowedChars and excludeChars leave no
+// letters of the needed case for. Letters of the other case would be
+// replaced by other characters, or left in the wrong case.
+func caseProblems(restrictions PasswordRestrictions) []string {
+	upper, lower := restrictions.charset(restrictions.upper()), restrictions.charset(restrictions.lower())
+	switch {
+	case restrictions.CaseMode == "upper" && upper == "" && lower != "":
+		return []string{"caseMode upper can't be satisfied because allowedChars and excludeChars leave no upper case letters"}
+	case restrictions.CaseMode == "lower" && lower == "" && upper != "":
+		return []string{"caseMode lower can't be satisfied because allowedChars and excludeChars leave no lower case letters"}
+	case restrictions.CaseMode == "random" && (upper == "" || lower == ""):
+		return []string{"caseMode random needs both upper and lower case letters left by allowedChars and excludeChars"}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+func chpasswdLines(usernames []string, restrictions PasswordRestrictions) (string, error) {
+	var lines strings.Builder
+	for _, username := range usernames {
+		if username == "" || strings.ContainsAny(username, ":\n") {
+			return "", fmt.Errorf("Username %q can't be empty or contain ':' or newlines", username)
+		}
+		password, _, err := generateWithinBudget(restrictions)
+		if err != nil {
+			return "", fmt.Errorf("User %q: %w", username, err)
+		}
+		fmt.Fprintf(&lines, "%s:%s\n", username, password)
+	}
+	return lines.String(), nil
+}
+
+func runChpasswd(lines string, stdout io.Writer) error {
+	cmd := exec.Command("chpasswd")
+	cmd.Stdin = strings.NewReader(lines)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("chpasswd failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	_, err := io.WriteString(stdout, lines)
+	return err
+}
+
+func chpasswd(usernames []string, restrictions PasswordRestrictions, apply bool) error {
+	lines, err := chpasswdLines(usernames, restrictions)
+	if err != nil {
+		return err
+	}
+	if apply {
+		return runChpasswd(lines, os.Stdout)
+	}
+	_, err = io.WriteString(os.Stdout, lines)
+	return err
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	claimIssuer          = "password_gen"
+	minClaimSecretLength = 32
+)
+
+type issuanceClaim struct {
+	Issuer     string                `json:"iss"`
+	Subject    string                `json:"sub"`
+	IssuedAt   int64                 `json:"iat"`
+	Expires    int64                 `json:"exp"`
+	ID         string                `json:"jti"`
+	PolicyHash string                `json:"policyHash"`
+	Policy     *PasswordRestrictions `json:"policy"`
+}
+
+// parseClaim returns the subject to issue a claim for, or "" when no claim
+// was requested.
+func parseClaim(query url.Values) (string, error) {
+	if query.Get("claim") == "" {
+		return "", nil
+	}
+	claim, err := strconv.ParseBool(query.Get("claim"))
+	if err != nil {
+		return "", errors.New("Parameter claim has to be a boolean")
+	}
+	if !claim {
+		return "", nil
+	}
+	if config.ClaimSecret == "" {
+		return "", errors.New("Claims aren't configured, set claimSecret")
+	}
+	if query.Get("subject") == "" {
+		return "", errors.New("Parameter subject is required with claim")
+	}
+	if query.Has("count") {
+		return "", errors.New("Parameter claim can't be combined with count")
+	}
+	return query.Get("subject"), nil
+}
+
+// claimPolicy is the policy embedded in claims. Anyone holding a claim can
+// read it, so parameters which are part of the password, like prefix and
+// suffix, or which describe the user are left out.
+func claimPolicy(restrictions *PasswordRestrictions) *PasswordRestrictions {
+	policy := *restrictions
+	policy.Prefix = ""
+	policy.Suffix = ""
+	policy.OldPassword = ""
+	policy.OldPasswordHash = ""
+	policy.UserInputs = ""
+	policy.Username = ""
+	policy.DisplayName = ""
+	return &policy
+}
+
+// issueClaim signs a JWT (HS256) asserting that a password satisfying
+// restrictions was issued for subject. It never contains the password.
+func issueClaim(subject string, restrictions *PasswordRestrictions, issuedAt time.Time) (string, error) {
+	restrictions = claimPolicy(restrictions)
+	policy, err := json.Marshal(restrictions)
+	if err != nil {
+		return "", err
+	}
+	policyHash := sha256.Sum256(policy)
+	id := make([]byte, 16)
+	if _, err = rand.Read(id); err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(issuanceClaim{
+		Issuer:     claimIssuer,
+		Subject:    subject,
+		IssuedAt:   issuedAt.Unix(),
+		Expires:    issuedAt.Add(config.ClaimTTL.Duration).Unix(),
+		ID:         hex.EncodeToString(id),
+		PolicyHash: "sha256:" + hex.EncodeToString(policyHash[:]),
+		Policy:     restrictions,
+	})
+	if err != nil {
+		return "", err
+	}
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(config.ClaimSecret))
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
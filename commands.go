@@ -0,0 +1,522 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"password_gen/markov_chain"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/montanaflynn/stats"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var configPath string
+
+var rootCmd = &cobra.Command{
+	Use:          "password_gen",
+	Short:        "Password generator service",
+	SilenceUsage: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		config, err = loadConfig(configPath)
+		if err != nil {
+			return err
+		}
+		breachChecker, err = newBreachChecker(config)
+		return err
+	},
+	RunE: runServe,
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the password generation api (default command)",
+	Args:  cobra.NoArgs,
+	RunE:  runServe,
+}
+
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate passwords to stdout",
+	Args:  cobra.NoArgs,
+	RunE:  runGenerate,
+}
+
+var trainCmd = &cobra.Command{
+	Use:   "train",
+	Short: "Train the markov chain model from the password dataset",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		caseFold, _ := cmd.Flags().GetBool("case-fold")
+		if err := markov_chain.GeneratePropablePasswordsModel(caseFold); err != nil {
+			return fmt.Errorf("Could not train data: %w", err)
+		}
+		return nil
+	},
+}
+
+var scoreCmd = &cobra.Command{
+	Use:   "score file",
+	Short: "Score passwords from a file, one per line, or from stdin when file is -",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runScore,
+}
+
+var breachFilterCmd = &cobra.Command{
+	Use:   "breach-filter file",
+	Short: "Build a bloom filter for breachProvider bloom from a breach list, one sha1 hash (hash:count) or password per line, or stdin when file is -",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runBreachFilter,
+}
+
+var chpasswdCmd = &cobra.Command{
+	Use:   "chpasswd username...",
+	Short: "Generate passwords for local users in the chpasswd format, or apply them with chpasswd",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		policy, _ := cmd.Flags().GetString("policy")
+		apply, _ := cmd.Flags().GetBool("apply")
+		restrictions, err := parsePolicy(policy)
+		if err != nil {
+			return err
+		}
+		return chpasswd(args, restrictions, apply)
+	},
+}
+
+var workerCmd = &cobra.Command{
+	Use:   "worker",
+	Short: "Answer generation requests from the NATS subject or Kafka topic busSubject",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runWorker(cmd.Context())
+	},
+}
+
+var modelCmd = &cobra.Command{
+	Use:   "model",
+	Short: "Inspect trained models",
+}
+
+var modelInfoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Describe the loaded model",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		info := markov_chain.DescribeModel()
+		if info.Error != "" {
+			return errors.New(info.Error)
+		}
+		fmt.Printf("name:       %s\npath:       %s\norder:      %d\nstates:     %d\nsize:       %d bytes\ntrained at: %s\ncase fold:  %t\n",
+			info.Name, info.Path, info.Order, info.States, info.SizeBytes, info.TrainedAt.Format(time.RFC3339), info.CaseFolded)
+		return nil
+	},
+}
+
+var modelDiffCmd = &cobra.Command{
+	Use:   "diff old.json new.json",
+	Short: "Compare two trained models",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runModelDiff,
+}
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Measure generator throughput in-process",
+	Args:  cobra.NoArgs,
+	RunE:  runBench,
+}
+
+var renderCmd = &cobra.Command{
+	Use:   "render template",
+	Short: "Render a template replacing password placeholders with generated secrets",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runRender,
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "path to a json config file")
+
+	for _, flags := range []*pflag.FlagSet{rootCmd.Flags(), serveCmd.Flags()} {
+		flags.String("admin-addr", "", "address of the admin listener exposing status, pprof and expvar, disabled when empty")
+		flags.String("base-path", "", "url path prefix of all routes, e.g. /api/passgen")
+		flags.String("trusted-proxies", "", "comma separated CIDRs of proxies allowed to set X-Forwarded-For and Forwarded headers")
+		flags.Bool("train", false, "train the model from the dataset before serving")
+		flags.Bool("case-fold", false, "lowercase the dataset when training with --train")
+	}
+
+	generateCmd.Flags().String("policy", "", "request parameters in query string format, e.g. \"minLength=12&userReadable=true\"")
+	generateCmd.Flags().Int("count", 1, "number of passwords to generate")
+	generateCmd.Flags().Bool("prefixes", false, "read prefixes from stdin, one per line, and generate a readable password starting with each of them")
+	generateCmd.Flags().Bool("terraform-external", false, "read a terraform external data source query from stdin and write the generated password to stdout")
+
+	trainCmd.Flags().Bool("case-fold", false, "lowercase the dataset before training and apply letter case at generation time")
+
+	modelDiffCmd.Flags().Int("samples", 5, "number of sample passwords generated from each model")
+
+	benchCmd.Flags().String("policy", "", "request parameters in query string format, e.g. \"minLength=12&userReadable=true\"")
+	benchCmd.Flags().Duration("duration", 10*time.Second, "how long to drive the generator")
+	benchCmd.Flags().Int("concurrency", runtime.GOMAXPROCS(0), "number of concurrent generators")
+
+	scoreCmd.Flags().String("policy", "", "request parameters in query string format to check the passwords against, e.g. \"minLength=12&minDigits=2\"")
+
+	chpasswdCmd.Flags().String("policy", "", "request parameters in query string format, e.g. \"minLength=12&minDigits=2\"")
+	chpasswdCmd.Flags().Bool("apply", false, "pipe the generated user:password lines to chpasswd, which requires root")
+
+	renderCmd.Flags().StringP("output", "o", "", "file to write the rendered template to, stdout when empty")
+
+	breachFilterCmd.Flags().StringP("output", "o", "breach.bloom", "file to write the bloom filter to")
+	breachFilterCmd.Flags().Uint64("count", 0, "number of entries in the breach list, counted with an extra pass when 0")
+	breachFilterCmd.Flags().Float64("false-positive-rate", 0.001, "rate of passwords wrongly reported as breached")
+
+	modelCmd.AddCommand(modelInfoCmd, modelDiffCmd)
+	rootCmd.AddCommand(serveCmd, generateCmd, trainCmd, modelCmd, benchCmd, renderCmd, scoreCmd, chpasswdCmd, breachFilterCmd, workerCmd)
+}
+
+// legacyFlags are the flags of the binary before it had subcommands, which
+// took a single dash.
+var legacyFlags = []string{"train", "config", "admin-addr", "trusted-proxies", "terraform-external"}
+
+// legacyArgs rewrites the single dash flags of the binary before it had
+// subcommands to their current form, so that deployments running
+// `password_gen -train` or `password_gen -terraform-external` keep working.
+func legacyArgs(args []string) []string {
+	rewritten := make([]string, 0, len(args)+1)
+	for i, arg := range args {
+		if arg == "--" {
+			return append(rewritten, args[i:]...)
+		}
+		for _, flag := range legacyFlags {
+			if arg == "-"+flag || strings.HasPrefix(arg, "-"+flag+"=") {
+				arg = "-" + arg
+				if flag == "terraform-external" {
+					rewritten = append([]string{"generate"}, rewritten...)
+				}
+				break
+			}
+		}
+		rewritten = append(rewritten, arg)
+	}
+	return rewritten
+}
+
+func parsePolicy(policy string) (PasswordRestrictions, error) {
+	query, err := url.ParseQuery(policy)
+	if err != nil {
+		return PasswordRestrictions{}, fmt.Errorf("Invalid policy: %w", err)
+	}
+	return parseRestrictions(query)
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	adminAddr, _ := cmd.Flags().GetString("admin-addr")
+	trustedProxyList, _ := cmd.Flags().GetString("trusted-proxies")
+	basePath, _ := cmd.Flags().GetString("base-path")
+	train, _ := cmd.Flags().GetBool("train")
+	if adminAddr != "" {
+		config.AdminAddr = adminAddr
+	}
+	if basePath != "" {
+		config.BasePath = normalizeBasePath(basePath)
+	}
+	if trustedProxyList != "" {
+		config.TrustedProxies = strings.Split(trustedProxyList, ",")
+	}
+	if train {
+		if err := trainCmd.RunE(cmd, args); err != nil {
+			return err
+		}
+	}
+	if err := config.validateServing(); err != nil {
+		return err
+	}
+	proxies, _ := parseTrustedProxies(strings.Join(config.TrustedProxies, ","))
+	var err error
+	if audit, err = newAuditLog(config); err != nil {
+		return err
+	}
+	if config.AdminAddr != "" {
+		go handleAdminRequests(config.AdminAddr)
+	}
+	if err := watchBlocklist(); err != nil {
+		return err
+	}
+	warnModelUnavailable()
+	scheduleModelAgeChecks()
+	toggleMaintenanceOnSignal()
+	handleRequests(proxies)
+	return nil
+}
+
+func runGenerate(cmd *cobra.Command, args []string) error {
+	if terraformExternal, _ := cmd.Flags().GetBool("terraform-external"); terraformExternal {
+		return runTerraformExternal(os.Stdin, os.Stdout)
+	}
+	policy, _ := cmd.Flags().GetString("policy")
+	count, _ := cmd.Flags().GetInt("count")
+	if prefixes, _ := cmd.Flags().GetBool("prefixes"); prefixes {
+		return generateFromPrefixes(os.Stdin, os.Stdout, os.Stderr, policy)
+	}
+	restrictions, err := parsePolicy(policy)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		password, _, err := generateWithinBudget(restrictions)
+		if err != nil {
+			return err
+		}
+		fmt.Println(password)
+	}
+	return nil
+}
+
+// generateFromPrefixes generates a readable password starting with each line
+// of stdin. Every line is parsed as the prefix parameter of the policy, so it's
+// validated like the prefix of a request. Lines which can't be satisfied are
+// reported on stderr and skipped.
+func generateFromPrefixes(stdin io.Reader, stdout io.Writer, stderr io.Writer, policy string) error {
+	query, err := url.ParseQuery(policy)
+	if err != nil {
+		return fmt.Errorf("Invalid policy: %w", err)
+	}
+	query.Set("userReadable", "true")
+	if _, err = parseRestrictions(query); err != nil {
+		return err
+	}
+	failed := 0
+	scanner := bufio.NewScanner(stdin)
+	for scanner.Scan() {
+		prefix := strings.TrimSpace(scanner.Text())
+		if prefix == "" {
+			continue
+		}
+		query.Set("prefix", prefix)
+		restrictions, err := parseRestrictions(query)
+		if err == nil && restrictions.Prefix == "" {
+			// The policy ignores prefixes, e.g. with mode=pin.
+			for _, warning := range restrictions.warnings {
+				if strings.HasPrefix(warning, "Parameter prefix") {
+					return fmt.Errorf("Policy can't generate passwords from prefixes: %s", warning)
+				}
+			}
+			return errors.New("Policy can't generate passwords from prefixes")
+		}
+		var password string
+		if err == nil {
+			password, _, err = generateWithinBudget(restrictions)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "Prefix %q: %v\n", prefix, err)
+			failed++
+			continue
+		}
+		fmt.Fprintln(stdout, password)
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("Passwords couldn't be generated for %d of the prefixes", failed)
+	}
+	return nil
+}
+
+func runModelDiff(cmd *cobra.Command, args []string) error {
+	samples, _ := cmd.Flags().GetInt("samples")
+	diff, err := markov_chain.CompareModels(args[0], args[1], samples)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%-12s %-30s %-30s\n", "", diff.Old.Path, diff.New.Path)
+	fmt.Printf("%-12s %-30d %-30d\n", "order", diff.Old.Order, diff.New.Order)
+	fmt.Printf("%-12s %-30d %-30d\n", "states", diff.Old.States, diff.New.States)
+	fmt.Printf("%-12s %-30s %-30s\n", "trained at", diff.Old.TrainedAt.Format("2006-01-02 15:04"), diff.New.TrainedAt.Format("2006-01-02 15:04"))
+	fmt.Println()
+
+	vocabularySize := len(diff.SharedVocabulary) + len(diff.OnlyOldVocabulary) + len(diff.OnlyNewVocabulary)
+	fmt.Printf("Vocabulary: %d shared, %d only in old, %d only in new", len(diff.SharedVocabulary), len(diff.OnlyOldVocabulary), len(diff.OnlyNewVocabulary))
+	if vocabularySize > 0 {
+		fmt.Printf(" (%.1f%% overlap)", 100*float64(len(diff.SharedVocabulary))/float64(vocabularySize))
+	}
+	fmt.Println()
+	if len(diff.OnlyOldVocabulary) > 0 {
+		fmt.Printf("  only in old: %s\n", strings.Join(diff.OnlyOldVocabulary, " "))
+	}
+	if len(diff.OnlyNewVocabulary) > 0 {
+		fmt.Printf("  only in new: %s\n", strings.Join(diff.OnlyNewVocabulary, " "))
+	}
+	fmt.Printf("States: %d shared, %d only in old, %d only in new\n", diff.SharedStates, diff.OnlyOldStates, diff.OnlyNewStates)
+	fmt.Printf("Transition divergence (Jensen-Shannon, bits): mean %.4f, max %.4f at %q\n", diff.MeanDivergence, diff.MaxDivergence, diff.MaxDivergenceState)
+	fmt.Println()
+
+	fmt.Printf("%-30s %-30s\n", "old samples", "new samples")
+	for i := range diff.OldSamples {
+		fmt.Printf("%-30s %-30s\n", diff.OldSamples[i], diff.NewSamples[i])
+	}
+	return nil
+}
+
+func runBench(cmd *cobra.Command, args []string) error {
+	policy, _ := cmd.Flags().GetString("policy")
+	duration, _ := cmd.Flags().GetDuration("duration")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	restrictions, err := parsePolicy(policy)
+	if err != nil {
+		return err
+	}
+	if concurrency < 1 {
+		return fmt.Errorf("concurrency has to be positive")
+	}
+
+	var before, after runtime.MemStats
+	var failures int64
+	latencies := make([][]float64, concurrency)
+	var wg sync.WaitGroup
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	deadline := start.Add(duration)
+	for worker := 0; worker < concurrency; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				began := time.Now()
+				_, _, err := generateWithinBudget(restrictions)
+				if err != nil {
+					atomic.AddInt64(&failures, 1)
+					continue
+				}
+				latencies[worker] = append(latencies[worker], float64(time.Since(began).Microseconds()))
+			}
+		}(worker)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	var all stats.Float64Data
+	for _, workerLatencies := range latencies {
+		all = append(all, workerLatencies...)
+	}
+	generated := len(all)
+	fmt.Printf("policy:        %s\n", policy)
+	fmt.Printf("duration:      %s, concurrency %d\n", elapsed.Round(time.Millisecond), concurrency)
+	fmt.Printf("generated:     %d passwords, %d failures\n", generated, failures)
+	fmt.Printf("throughput:    %.1f passwords/sec\n", float64(generated)/elapsed.Seconds())
+	if generated == 0 {
+		return nil
+	}
+	fmt.Printf("allocations:   %.1f allocs/password, %.1f bytes/password, %d GC cycles\n",
+		float64(after.Mallocs-before.Mallocs)/float64(generated),
+		float64(after.TotalAlloc-before.TotalAlloc)/float64(generated),
+		after.NumGC-before.NumGC)
+	fmt.Print("latency (µs):  ")
+	for _, percentile := range []float64{50, 90, 99} {
+		value, _ := all.Percentile(percentile)
+		fmt.Printf("p%.0f %.0f  ", percentile, value)
+	}
+	max, _ := all.Max()
+	fmt.Printf("max %.0f\n", max)
+	return nil
+}
+
+func runRender(cmd *cobra.Command, args []string) error {
+	output, _ := cmd.Flags().GetString("output")
+	template, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	rendered, err := renderTemplate(string(template))
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		fmt.Print(rendered)
+		return nil
+	}
+	return os.WriteFile(output, []byte(rendered), 0600)
+}
+
+func runScore(cmd *cobra.Command, args []string) error {
+	var restrictions *PasswordRestrictions
+	if policy, _ := cmd.Flags().GetString("policy"); policy != "" {
+		parsed, err := parsePolicy(policy)
+		if err != nil {
+			return err
+		}
+		restrictions = &parsed
+	}
+	if args[0] == "-" {
+		return scorePasswords(os.Stdin, os.Stdout, restrictions)
+	}
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return scorePasswords(file, os.Stdout, restrictions)
+}
+
+func runBreachFilter(cmd *cobra.Command, args []string) error {
+	output, _ := cmd.Flags().GetString("output")
+	count, _ := cmd.Flags().GetUint64("count")
+	rate, _ := cmd.Flags().GetFloat64("false-positive-rate")
+	if rate <= 0 || rate >= 1 {
+		return errors.New("--false-positive-rate has to be between 0 and 1")
+	}
+	input := os.Stdin
+	if args[0] != "-" {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+	if count == 0 {
+		if input == os.Stdin {
+			return errors.New("--count is needed when reading the breach list from stdin")
+		}
+		scanner := bufio.NewScanner(input)
+		for scanner.Scan() {
+			count++
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+		if _, err := input.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	filter, added, err := buildBloomFilter(input, count, rate)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err = filter.writeTo(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d entries written to %s (%d bytes, %d hashes)\n", added, output, len(filter.bits), filter.k)
+	return nil
+}
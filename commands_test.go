@@ -0,0 +1,48 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLegacyArgs(t *testing.T) {
+	for _, test := range []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"-train"}, []string{"--train"}},
+		{[]string{"-config", "c.json", "-train"}, []string{"--config", "c.json", "--train"}},
+		{[]string{"-config=c.json", "-admin-addr", ":6060"}, []string{"--config=c.json", "--admin-addr", ":6060"}},
+		{[]string{"-config", "c.json", "-terraform-external"}, []string{"generate", "--config", "c.json", "--terraform-external"}},
+		{[]string{"serve", "--train"}, []string{"serve", "--train"}},
+		{[]string{"render", "-o", ".env", "t"}, []string{"render", "-o", ".env", "t"}},
+		{[]string{"score", "--", "-train"}, []string{"score", "--", "-train"}},
+	} {
+		if got := legacyArgs(test.args); !slices.Equal(got, test.want) {
+			t.Errorf("legacyArgs(%q) = %q, want %q", test.args, got, test.want)
+		}
+	}
+}
+
+func TestGenerateFromPrefixesValidatesEachPrefix(t *testing.T) {
+	var stdout, stderr strings.Builder
+	err := generateFromPrefixes(strings.NewReader("verylongusername\nzed\nålice\n"), &stdout, &stderr, "maxLength=12&excludeChars=z")
+	if err == nil {
+		t.Error("Invalid prefixes didn't fail the command")
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("Invalid prefixes generated %q", stdout.String())
+	}
+	if lines := strings.Count(stderr.String(), "\n"); lines != 3 {
+		t.Errorf("%d of 3 invalid prefixes reported: %q", lines, stderr.String())
+	}
+}
+
+func TestGenerateFromPrefixesRejectsPoliciesIgnoringThem(t *testing.T) {
+	var stdout, stderr strings.Builder
+	err := generateFromPrefixes(strings.NewReader("alice\n"), &stdout, &stderr, "mode=pin")
+	if err == nil || stdout.Len() != 0 {
+		t.Errorf("mode=pin generated %q, error %v", stdout.String(), err)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"password_gen/dictionary"
+	"strconv"
+	"unicode"
+)
+
+type Composition struct {
+	Length      int     `json:"length"`
+	Lower       int     `json:"lower"`
+	Upper       int     `json:"upper"`
+	Digits      int     `json:"digits"`
+	Specials    int     `json:"specials"`
+	EntropyBits float64 `json:"entropyBits"`
+	Zxcvbn      int     `json:"zxcvbn"`
+}
+
+var compositionColumns = []string{"length", "lower", "upper", "digits", "specials", "entropyBits", "zxcvbn"}
+
+func parseComposition(query url.Values) (bool, error) {
+	if query.Get("composition") == "" {
+		return false, nil
+	}
+	composition, err := strconv.ParseBool(query.Get("composition"))
+	if err != nil {
+		return false, errors.New("Parameter composition has to be a boolean")
+	}
+	return composition, nil
+}
+
+func describeComposition(password string) Composition {
+	composition := Composition{
+		EntropyBits: dictionary.Analyze(password).EffectiveEntropyBits,
+		Zxcvbn:      zxcvbnScore(password),
+	}
+	for _, r := range password {
+		composition.Length++
+		switch {
+		case r >= '0' && r <= '9':
+			composition.Digits++
+		case unicode.IsUpper(r):
+			composition.Upper++
+		case unicode.IsLower(r):
+			composition.Lower++
+		default:
+			composition.Specials++
+		}
+	}
+	return composition
+}
+
+func (c Composition) columns() []string {
+	return []string{
+		strconv.Itoa(c.Length),
+		strconv.Itoa(c.Lower),
+		strconv.Itoa(c.Upper),
+		strconv.Itoa(c.Digits),
+		strconv.Itoa(c.Specials),
+		strconv.FormatFloat(c.EntropyBits, 'f', 1, 64),
+		strconv.Itoa(c.Zxcvbn),
+	}
+}
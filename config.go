@@ -0,0 +1,291 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/schema"
+)
+
+type Config struct {
+	AdminAddr      string            `json:"adminAddr"`
+	BasePath       string            `json:"basePath"`
+	TrustedProxies []string          `json:"trustedProxies"`
+	Defaults       map[string]string `json:"defaults"`
+	MinLengthFloor int               `json:"minLengthFloor"`
+	MaxLengthCap   int               `json:"maxLengthCap"`
+
+	MaxBodyBytes       int64 `json:"maxBodyBytes"`
+	MaxParameterLength int   `json:"maxParameterLength"`
+	MaxBatchCount      int   `json:"maxBatchCount"`
+
+	GenerationTimeout Duration `json:"generationTimeout"`
+	ReadableWorkers   int      `json:"readableWorkers"`
+	ReadableQueue     int      `json:"readableQueue"`
+	IdempotencyTTL    Duration `json:"idempotencyTTL"`
+	SessionTTL        Duration `json:"sessionTTL"`
+
+	Timeouts map[string]Duration `json:"timeouts"`
+
+	MinReadableGuesses float64 `json:"minReadableGuesses"`
+	MinEntropyBits     float64 `json:"minEntropyBits"`
+
+	WordlistDir   string `json:"wordlistDir"`
+	BlocklistFile string `json:"blocklistFile"`
+	AdminToken    string `json:"adminToken"`
+
+	ClaimSecret string   `json:"claimSecret"`
+	ClaimTTL    Duration `json:"claimTTL"`
+
+	BreachProvider string `json:"breachProvider"`
+	BreachURL      string `json:"breachURL"`
+	BreachFilter   string `json:"breachFilter"`
+
+	MaintenanceMessage string `json:"maintenanceMessage"`
+
+	MaxModelAge     Duration `json:"maxModelAge"`
+	ModelAgeAction  string   `json:"modelAgeAction"`
+	ModelAgeWebhook string   `json:"modelAgeWebhook"`
+
+	AuditSink   string `json:"auditSink"`
+	AuditTarget string `json:"auditTarget"`
+	AuditFormat string `json:"auditFormat"`
+
+	BusTransport  string `json:"busTransport"`
+	BusURL        string `json:"busURL"`
+	BusSubject    string `json:"busSubject"`
+	BusGroup      string `json:"busGroup"`
+	BusReplyTopic string `json:"busReplyTopic"`
+}
+
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+var config = defaultConfig()
+
+func defaultConfig() Config {
+	return Config{
+		Defaults:           map[string]string{"maxLength": "16"},
+		MaxBodyBytes:       1 << 20,
+		MaxParameterLength: 1024,
+		MaxBatchCount:      1000,
+		IdempotencyTTL:     Duration{time.Hour},
+		SessionTTL:         Duration{15 * time.Minute},
+		ClaimTTL:           Duration{5 * time.Minute},
+		BusGroup:           "password_gen",
+	}
+}
+
+func loadConfig(path string) (Config, error) {
+	loaded := defaultConfig()
+	if path == "" {
+		return loaded, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return loaded, err
+	}
+	if err = json.Unmarshal(data, &loaded); err != nil {
+		return loaded, fmt.Errorf("Could not parse config %s: %w", path, err)
+	}
+	loaded.BasePath = normalizeBasePath(loaded.BasePath)
+	if problems := loaded.problems(); len(problems) > 0 {
+		return loaded, fmt.Errorf("Invalid config %s: %s", path, strings.Join(problems, "; "))
+	}
+	return loaded, nil
+}
+
+func (c Config) problems() []string {
+	var problems []string
+	if c.MinLengthFloor < 0 {
+		problems = append(problems, fmt.Sprintf("minLengthFloor (%d) can't be negative", c.MinLengthFloor))
+	}
+	if c.MaxLengthCap < 0 || (c.MaxLengthCap > 0 && c.MaxLengthCap < c.MinLengthFloor) {
+		problems = append(problems, fmt.Sprintf("maxLengthCap (%d) can't be negative or smaller than minLengthFloor (%d)", c.MaxLengthCap, c.MinLengthFloor))
+	}
+	if c.MaxBodyBytes < 0 {
+		problems = append(problems, fmt.Sprintf("maxBodyBytes (%d) can't be negative", c.MaxBodyBytes))
+	}
+	if c.MaxParameterLength < 0 {
+		problems = append(problems, fmt.Sprintf("maxParameterLength (%d) can't be negative", c.MaxParameterLength))
+	}
+	if c.MaxBatchCount < 1 {
+		problems = append(problems, fmt.Sprintf("maxBatchCount (%d) has to be at least 1", c.MaxBatchCount))
+	}
+	if c.GenerationTimeout.Duration < 0 {
+		problems = append(problems, fmt.Sprintf("generationTimeout (%s) can't be negative", c.GenerationTimeout))
+	}
+	if c.ReadableWorkers < 0 {
+		problems = append(problems, fmt.Sprintf("readableWorkers (%d) can't be negative", c.ReadableWorkers))
+	}
+	if c.ReadableQueue < 0 {
+		problems = append(problems, fmt.Sprintf("readableQueue (%d) can't be negative", c.ReadableQueue))
+	}
+	if c.MinReadableGuesses < 0 {
+		problems = append(problems, fmt.Sprintf("minReadableGuesses (%g) can't be negative", c.MinReadableGuesses))
+	}
+	if c.MinEntropyBits < 0 {
+		problems = append(problems, fmt.Sprintf("minEntropyBits (%g) can't be negative", c.MinEntropyBits))
+	}
+	if c.IdempotencyTTL.Duration < 0 {
+		problems = append(problems, fmt.Sprintf("idempotencyTTL (%s) can't be negative", c.IdempotencyTTL))
+	}
+	if c.SessionTTL.Duration < 0 {
+		problems = append(problems, fmt.Sprintf("sessionTTL (%s) can't be negative", c.SessionTTL))
+	}
+	if c.MaxModelAge.Duration < 0 {
+		problems = append(problems, fmt.Sprintf("maxModelAge (%s) can't be negative", c.MaxModelAge))
+	}
+	if !slices.Contains([]string{"", "warn", "retrain"}, c.ModelAgeAction) {
+		problems = append(problems, fmt.Sprintf("modelAgeAction has to be warn or retrain, not %q", c.ModelAgeAction))
+	}
+	if c.ModelAgeWebhook != "" {
+		if webhook, err := url.Parse(c.ModelAgeWebhook); err != nil || (webhook.Scheme != "http" && webhook.Scheme != "https") || webhook.Host == "" {
+			problems = append(problems, "modelAgeWebhook has to be an http or https url")
+		}
+	}
+	if c.ClaimSecret != "" && len(c.ClaimSecret) < minClaimSecretLength {
+		problems = append(problems, fmt.Sprintf("claimSecret has to be at least %d bytes long", minClaimSecretLength))
+	}
+	if c.ClaimTTL.Duration <= 0 {
+		problems = append(problems, fmt.Sprintf("claimTTL (%s) has to be positive", c.ClaimTTL))
+	}
+	if !slices.Contains([]string{"", "none", "hibp", "bloom"}, c.BreachProvider) {
+		problems = append(problems, fmt.Sprintf("breachProvider has to be none, hibp or bloom, not %q", c.BreachProvider))
+	}
+	if c.BreachProvider == "bloom" && c.BreachFilter == "" {
+		problems = append(problems, "breachProvider bloom needs breachFilter")
+	}
+	if !slices.Contains([]string{"", "none", "file", "tcp", "kafka"}, c.AuditSink) {
+		problems = append(problems, fmt.Sprintf("auditSink has to be none, file, tcp or kafka, not %q", c.AuditSink))
+	} else if c.AuditSink != "" && c.AuditSink != "none" && c.AuditTarget == "" {
+		problems = append(problems, fmt.Sprintf("auditSink %s needs auditTarget", c.AuditSink))
+	}
+	if !slices.Contains([]string{"", "json", "cef"}, c.AuditFormat) {
+		problems = append(problems, fmt.Sprintf("auditFormat has to be json or cef, not %q", c.AuditFormat))
+	}
+	if !slices.Contains([]string{"", "none", "nats", "kafka"}, c.BusTransport) {
+		problems = append(problems, fmt.Sprintf("busTransport has to be none, nats or kafka, not %q", c.BusTransport))
+	} else if c.BusTransport != "" && c.BusTransport != "none" {
+		if c.BusURL == "" || c.BusSubject == "" || c.BusGroup == "" {
+			problems = append(problems, fmt.Sprintf("busTransport %s needs busURL, busSubject and busGroup", c.BusTransport))
+		}
+		if c.BusTransport == "kafka" && c.BusReplyTopic == "" {
+			problems = append(problems, "busTransport kafka needs busReplyTopic")
+		}
+		if c.BusURL != "" {
+			if busURL, err := url.Parse(c.BusURL); err != nil || busURL.Host == "" {
+				problems = append(problems, "busURL has to be a url")
+			} else if c.BusTransport == "nats" && busURL.Scheme != "nats" {
+				// TLS isn't supported, the connection would be plaintext.
+				problems = append(problems, fmt.Sprintf("busURL of busTransport nats has to be a nats:// url, not %s://", busURL.Scheme))
+			} else if c.BusTransport == "kafka" && busURL.Scheme != "http" && busURL.Scheme != "https" {
+				problems = append(problems, "busURL of busTransport kafka has to be an http or https url")
+			}
+		}
+	}
+	for class, timeout := range c.Timeouts {
+		if !slices.Contains(timeoutClasses, class) {
+			problems = append(problems, fmt.Sprintf("timeouts contain unknown endpoint class %q, known classes are %s", class, strings.Join(timeoutClasses, ", ")))
+		} else if timeout.Duration < 0 {
+			problems = append(problems, fmt.Sprintf("timeouts.%s (%s) can't be negative", class, timeout))
+		}
+	}
+	if _, err := parseTrustedProxies(strings.Join(c.TrustedProxies, ",")); err != nil {
+		problems = append(problems, "trustedProxies: "+err.Error())
+	}
+
+	var restrictions PasswordRestrictions
+	if err := schema.NewDecoder().Decode(&restrictions, c.queryDefaults()); err != nil {
+		problems = append(problems, "defaults are invalid: "+err.Error())
+	} else if _, err := c.parseRestrictions(url.Values{}); err != nil {
+		problems = append(problems, "defaults are invalid: "+err.Error())
+	}
+	return problems
+}
+
+func (c Config) validateServing() error {
+	if problems := c.servingProblems(); len(problems) > 0 {
+		return fmt.Errorf("Can't start serving: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (c Config) servingProblems() []string {
+	problems := c.problems()
+	if c.WordlistDir != "" {
+		if stat, err := os.Stat(c.WordlistDir); err != nil || !stat.IsDir() {
+			problems = append(problems, fmt.Sprintf("wordlistDir %s isn't a directory", c.WordlistDir))
+		}
+	}
+	if c.BlocklistFile != "" {
+		if stat, err := os.Stat(filepath.Dir(c.BlocklistFile)); err != nil || !stat.IsDir() {
+			problems = append(problems, fmt.Sprintf("blocklistFile %s isn't in a directory", c.BlocklistFile))
+		}
+	}
+	return problems
+}
+
+func normalizeBasePath(basePath string) string {
+	basePath = strings.Trim(strings.TrimSpace(basePath), "/")
+	if basePath == "" {
+		return ""
+	}
+	return "/" + basePath
+}
+
+func (c Config) queryDefaults() url.Values {
+	query := url.Values{}
+	for key, value := range c.Defaults {
+		query.Set(key, value)
+	}
+	return query
+}
+
+func (c Config) defaultedWarnings(query url.Values) []string {
+	var warnings []string
+	keys := make([]string, 0, len(c.Defaults))
+	for key := range c.Defaults {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !query.Has(key) {
+			warnings = append(warnings, fmt.Sprintf("Parameter %s defaulted to %s", key, c.Defaults[key]))
+		}
+	}
+	return warnings
+}
+
+func (c Config) withDefaults(query url.Values) url.Values {
+	merged := c.queryDefaults()
+	for key, values := range query {
+		merged[key] = values
+	}
+	return merged
+}
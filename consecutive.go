@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+func (restrictions PasswordRestrictions) limitsConsecutive() bool {
+	return restrictions.MaxConsecutiveIdentical > 0 && restrictions.Mode == "" && restrictions.PassphrasePattern == "" && restrictions.Pattern == ""
+}
+
+// consecutiveRun is the length of the run of identical characters ending at
+// position i.
+func consecutiveRun(password []rune, i int) int {
+	run := 1
+	for j := i - 1; j >= 0 && password[j] == password[i]; j-- {
+		run++
+	}
+	return run
+}
+
+func hasConsecutiveIdentical(password string, limit int) bool {
+	runes := []rune(password)
+	for i := range runes {
+		if consecutiveRun(runes, i) > limit {
+			return true
+		}
+	}
+	return false
+}
+
+// repairConsecutive replaces characters extending a run of identical
+// characters over maxConsecutiveIdentical with another character of the
+// same class, or of any class when the class has no other one.
+func repairConsecutive(password string, restrictions PasswordRestrictions) (string, error) {
+	repaired := []rune(password)
+	start, end := restrictions.generated(len(repaired))
+	limit := restrictions.MaxConsecutiveIdentical
+	// Runs are repaired from left to right, except for the one the suffix
+	// starts, which the last generated character can't extend too far.
+	suffixRun := 0
+	for j := end; j < len(repaired) && repaired[j] == repaired[end]; j++ {
+		suffixRun++
+	}
+	extendsSuffix := func(i int, ch rune) bool {
+		return i+1 == end && suffixRun > 0 && ch == repaired[end]
+	}
+	count := 0
+	for i := start; i < end; i++ {
+		run := consecutiveRun(repaired, i)
+		if extendsSuffix(i, repaired[i]) {
+			run += suffixRun
+		}
+		if run <= limit {
+			continue
+		}
+		differing := func(charset string) string {
+			return strings.Map(func(ch rune) rune {
+				if ch == repaired[i] || (extendsSuffix(i, ch) && suffixRun+1 > limit) {
+					return -1
+				}
+				return ch
+			}, charset)
+		}
+		charset := differing(restrictions.classCharset(repaired[i]))
+		if charset == "" {
+			charset = restrictions.spacedCharset(repaired, i, differing(restrictions.baseCharset()))
+		}
+		if charset == "" {
+			continue
+		}
+		ch, err := randomRune(charset)
+		if err != nil {
+			return "", err
+		}
+		repaired[i] = ch
+		count++
+	}
+	if count > 0 {
+		recordRepair("consecutive", count)
+	}
+	return string(repaired), nil
+}
+
+func checkConsecutive(password string, restrictions PasswordRestrictions) error {
+	if hasConsecutiveIdentical(password, restrictions.MaxConsecutiveIdentical) {
+		return violation("consecutive", fmt.Errorf("Generated password has more identical characters in a row than maxConsecutiveIdentical (%d) allows, try again", restrictions.MaxConsecutiveIdentical))
+	}
+	return nil
+}
+
+func consecutiveProblems(restrictions PasswordRestrictions) []string {
+	if restrictions.MaxConsecutiveIdentical <= 0 {
+		return nil
+	}
+	var problems []string
+	if !restrictions.UserReadable && utf8.RuneCountInString(restrictions.baseCharset()) < 2 && restrictions.MaxLength > restrictions.MaxConsecutiveIdentical {
+		problems = append(problems, "maxConsecutiveIdentical needs at least 2 characters left by allowedChars and excludeChars")
+	}
+	if hasConsecutiveIdentical(restrictions.Prefix, restrictions.MaxConsecutiveIdentical) || hasConsecutiveIdentical(restrictions.Suffix, restrictions.MaxConsecutiveIdentical) {
+		problems = append(problems, fmt.Sprintf("prefix or suffix repeats a character more than maxConsecutiveIdentical (%d) times in a row", restrictions.MaxConsecutiveIdentical))
+	}
+	return problems
+}
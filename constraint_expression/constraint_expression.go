@@ -0,0 +1,612 @@
+package constraint_expression
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type kind int
+
+const (
+	kindBool kind = iota
+	kindInt
+	kindString
+	kindClass
+	kindPattern
+)
+
+func (k kind) String() string {
+	switch k {
+	case kindBool:
+		return "bool"
+	case kindInt:
+		return "number"
+	case kindString:
+		return "string"
+	case kindClass:
+		return "character class"
+	default:
+		return "pattern"
+	}
+}
+
+type value struct {
+	kind kind
+	b    bool
+	i    int
+	s    string
+	re   *regexp.Regexp
+}
+
+var patterns = map[string]*regexp.Regexp{
+	"year":     regexp.MustCompile(`(19|20)[0-9]{2}`),
+	"sequence": regexp.MustCompile(`(?i)(abc|bcd|cde|def|efg|fgh|ghi|hij|ijk|jkl|klm|lmn|mno|nop|opq|pqr|qrs|rst|stu|tuv|uvw|vwx|wxy|xyz|012|123|234|345|456|567|678|789)`),
+}
+
+const (
+	// maxNesting bounds the parentheses, function calls and negations the
+	// parser, type checker and evaluator recurse into.
+	maxNesting = 32
+	// maxTokens bounds the length of operator chains, which nest the same
+	// way, whatever the limit on the length of parameters.
+	maxTokens = 512
+)
+
+type Expression struct {
+	source  string
+	root    node
+	classes map[string]string
+}
+
+type environment struct {
+	password string
+	classes  map[string]string
+}
+
+// Compile parses source and type checks it against the given character
+// classes, so that Evaluate can never fail at generation time.
+func Compile(source string, classes map[string]string) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) > maxTokens {
+		return nil, fmt.Errorf("Expression can't have more than %d tokens", maxTokens)
+	}
+	p := parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEnd {
+		return nil, fmt.Errorf("Unexpected %q at position %d", p.peek().text, p.peek().pos)
+	}
+	k, err := root.check(classes)
+	if err != nil {
+		return nil, err
+	}
+	if k != kindBool {
+		return nil, fmt.Errorf("Expression must evaluate to bool, got %s", k)
+	}
+	return &Expression{source: source, root: root, classes: classes}, nil
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+func (e *Expression) Evaluate(password string) bool {
+	return e.root.eval(environment{password: password, classes: e.classes}).b
+}
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenIdent
+	tokenInt
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", ",", "+", "-"}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		r, size := utf8.DecodeRuneInString(source[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(source) {
+				r, size = utf8.DecodeRuneInString(source[i:])
+				if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+					break
+				}
+				i += size
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: source[start:i], pos: start})
+		case r >= '0' && r <= '9':
+			start := i
+			for i < len(source) && source[i] >= '0' && source[i] <= '9' {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenInt, text: source[start:i], pos: start})
+		case r == '"' || r == '\'':
+			start := i
+			i++
+			var sb strings.Builder
+			closed := false
+			for i < len(source) {
+				c := source[i]
+				if c == '\\' && i+1 < len(source) {
+					sb.WriteByte(source[i+1])
+					i += 2
+					continue
+				}
+				i++
+				if rune(c) == r {
+					closed = true
+					break
+				}
+				sb.WriteByte(c)
+			}
+			if !closed {
+				return nil, fmt.Errorf("Unterminated string at position %d", start)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: sb.String(), pos: start})
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(source[i:], op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("Unexpected character %q at position %d", r, i)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEnd, pos: len(source)}), nil
+}
+
+type parser struct {
+	tokens  []token
+	current int
+	depth   int
+}
+
+func (p *parser) nest() error {
+	p.depth++
+	if p.depth > maxNesting {
+		return fmt.Errorf("Expression can't be nested deeper than %d levels at position %d", maxNesting, p.peek().pos)
+	}
+	return nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.current]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.current]
+	if t.kind != tokenEnd {
+		p.current++
+	}
+	return t
+}
+
+func (p *parser) acceptOperator(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.next()
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOperator("||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "||", left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOperator("&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "&&", left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if _, ok := p.acceptOperator("!"); ok {
+		defer func() { p.depth-- }()
+		if err := p.nest(); err != nil {
+			return nil, err
+		}
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.acceptOperator("==", "!=", "<=", ">=", "<", ">")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	return binaryNode{op: op, left: left, right: right}, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOperator("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenInt:
+		i, err := strconv.Atoi(t.text)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid number %q at position %d", t.text, t.pos)
+		}
+		return literalNode{value: value{kind: kindInt, i: i}}, nil
+	case tokenString:
+		return literalNode{value: value{kind: kindString, s: t.text}}, nil
+	case tokenIdent:
+		if t.text == "true" || t.text == "false" {
+			return literalNode{value: value{kind: kindBool, b: t.text == "true"}}, nil
+		}
+		if _, ok := p.acceptOperator("("); !ok {
+			return identifierNode{name: t.text, pos: t.pos}, nil
+		}
+		defer func() { p.depth-- }()
+		if err := p.nest(); err != nil {
+			return nil, err
+		}
+		call := callNode{name: t.text, pos: t.pos}
+		if _, ok := p.acceptOperator(")"); ok {
+			return call, nil
+		}
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if _, ok := p.acceptOperator(")"); ok {
+				return call, nil
+			}
+			if _, ok := p.acceptOperator(","); !ok {
+				return nil, fmt.Errorf("Expected \",\" or \")\" at position %d", p.peek().pos)
+			}
+		}
+	case tokenOperator:
+		if t.text == "(" {
+			defer func() { p.depth-- }()
+			if err := p.nest(); err != nil {
+				return nil, err
+			}
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.acceptOperator(")"); !ok {
+				return nil, fmt.Errorf("Expected \")\" at position %d", p.peek().pos)
+			}
+			return inner, nil
+		}
+	case tokenEnd:
+		return nil, errors.New("Unexpected end of expression")
+	}
+	return nil, fmt.Errorf("Unexpected %q at position %d", t.text, t.pos)
+}
+
+type node interface {
+	check(classes map[string]string) (kind, error)
+	eval(env environment) value
+}
+
+type literalNode struct {
+	value value
+}
+
+func (n literalNode) check(classes map[string]string) (kind, error) {
+	return n.value.kind, nil
+}
+
+func (n literalNode) eval(env environment) value {
+	return n.value
+}
+
+type identifierNode struct {
+	name string
+	pos  int
+}
+
+func (n identifierNode) check(classes map[string]string) (kind, error) {
+	switch n.name {
+	case "password":
+		return kindString, nil
+	case "length":
+		return kindInt, nil
+	}
+	if _, ok := classes[n.name]; ok {
+		return kindClass, nil
+	}
+	if _, ok := patterns[n.name]; ok {
+		return kindPattern, nil
+	}
+	return 0, fmt.Errorf("Unknown identifier %q at position %d", n.name, n.pos)
+}
+
+func (n identifierNode) eval(env environment) value {
+	switch n.name {
+	case "password":
+		return value{kind: kindString, s: env.password}
+	case "length":
+		return value{kind: kindInt, i: utf8.RuneCountInString(env.password)}
+	}
+	if chars, ok := env.classes[n.name]; ok {
+		return value{kind: kindClass, s: chars}
+	}
+	return value{kind: kindPattern, re: patterns[n.name]}
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n notNode) check(classes map[string]string) (kind, error) {
+	k, err := n.operand.check(classes)
+	if err != nil {
+		return 0, err
+	}
+	if k != kindBool {
+		return 0, fmt.Errorf("Operator \"!\" expects bool, got %s", k)
+	}
+	return kindBool, nil
+}
+
+func (n notNode) eval(env environment) value {
+	return value{kind: kindBool, b: !n.operand.eval(env).b}
+}
+
+type binaryNode struct {
+	op    string
+	left  node
+	right node
+}
+
+func (n binaryNode) check(classes map[string]string) (kind, error) {
+	left, err := n.left.check(classes)
+	if err != nil {
+		return 0, err
+	}
+	right, err := n.right.check(classes)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case "&&", "||":
+		if left != kindBool || right != kindBool {
+			return 0, fmt.Errorf("Operator %q expects bool operands, got %s and %s", n.op, left, right)
+		}
+		return kindBool, nil
+	case "+", "-":
+		if left != kindInt || right != kindInt {
+			return 0, fmt.Errorf("Operator %q expects number operands, got %s and %s", n.op, left, right)
+		}
+		return kindInt, nil
+	case "==", "!=":
+		if left != right || left == kindClass || left == kindPattern {
+			return 0, fmt.Errorf("Operator %q can't compare %s and %s", n.op, left, right)
+		}
+		return kindBool, nil
+	default:
+		if left != kindInt || right != kindInt {
+			return 0, fmt.Errorf("Operator %q expects number operands, got %s and %s", n.op, left, right)
+		}
+		return kindBool, nil
+	}
+}
+
+func (n binaryNode) eval(env environment) value {
+	left := n.left.eval(env)
+	switch n.op {
+	case "&&":
+		if !left.b {
+			return left
+		}
+		return n.right.eval(env)
+	case "||":
+		if left.b {
+			return left
+		}
+		return n.right.eval(env)
+	}
+	right := n.right.eval(env)
+	switch n.op {
+	case "+":
+		return value{kind: kindInt, i: left.i + right.i}
+	case "-":
+		return value{kind: kindInt, i: left.i - right.i}
+	case "==":
+		return value{kind: kindBool, b: left == right}
+	case "!=":
+		return value{kind: kindBool, b: left != right}
+	case "<":
+		return value{kind: kindBool, b: left.i < right.i}
+	case "<=":
+		return value{kind: kindBool, b: left.i <= right.i}
+	case ">":
+		return value{kind: kindBool, b: left.i > right.i}
+	default:
+		return value{kind: kindBool, b: left.i >= right.i}
+	}
+}
+
+type function struct {
+	accepts []kind
+	returns kind
+	call    func(env environment, arg value) value
+}
+
+var functions = map[string]function{
+	"count": {
+		accepts: []kind{kindClass, kindString, kindPattern},
+		returns: kindInt,
+		call: func(env environment, arg value) value {
+			switch arg.kind {
+			case kindClass:
+				n := 0
+				for _, r := range env.password {
+					if strings.ContainsRune(arg.s, r) {
+						n++
+					}
+				}
+				return value{kind: kindInt, i: n}
+			case kindPattern:
+				return value{kind: kindInt, i: len(arg.re.FindAllStringIndex(env.password, -1))}
+			default:
+				if arg.s == "" {
+					return value{kind: kindInt}
+				}
+				return value{kind: kindInt, i: strings.Count(env.password, arg.s)}
+			}
+		},
+	},
+	"contains": {
+		accepts: []kind{kindClass, kindString, kindPattern},
+		returns: kindBool,
+		call: func(env environment, arg value) value {
+			switch arg.kind {
+			case kindClass:
+				return value{kind: kindBool, b: strings.ContainsAny(env.password, arg.s)}
+			case kindPattern:
+				return value{kind: kindBool, b: arg.re.MatchString(env.password)}
+			default:
+				return value{kind: kindBool, b: strings.Contains(env.password, arg.s)}
+			}
+		},
+	},
+	"startsWith": {
+		accepts: []kind{kindClass, kindString},
+		returns: kindBool,
+		call: func(env environment, arg value) value {
+			if arg.kind == kindString {
+				return value{kind: kindBool, b: strings.HasPrefix(env.password, arg.s)}
+			}
+			r, _ := utf8.DecodeRuneInString(env.password)
+			return value{kind: kindBool, b: env.password != "" && strings.ContainsRune(arg.s, r)}
+		},
+	},
+	"endsWith": {
+		accepts: []kind{kindClass, kindString},
+		returns: kindBool,
+		call: func(env environment, arg value) value {
+			if arg.kind == kindString {
+				return value{kind: kindBool, b: strings.HasSuffix(env.password, arg.s)}
+			}
+			r, _ := utf8.DecodeLastRuneInString(env.password)
+			return value{kind: kindBool, b: env.password != "" && strings.ContainsRune(arg.s, r)}
+		},
+	},
+}
+
+type callNode struct {
+	name string
+	pos  int
+	args []node
+}
+
+func (n callNode) check(classes map[string]string) (kind, error) {
+	fn, ok := functions[n.name]
+	if !ok {
+		return 0, fmt.Errorf("Unknown function %q at position %d", n.name, n.pos)
+	}
+	if len(n.args) != 1 {
+		return 0, fmt.Errorf("Function %q expects 1 argument, got %d", n.name, len(n.args))
+	}
+	k, err := n.args[0].check(classes)
+	if err != nil {
+		return 0, err
+	}
+	for _, accepted := range fn.accepts {
+		if k == accepted {
+			return fn.returns, nil
+		}
+	}
+	return 0, fmt.Errorf("Function %q doesn't accept %s", n.name, k)
+}
+
+func (n callNode) eval(env environment) value {
+	return functions[n.name].call(env, n.args[0].eval(env))
+}
@@ -0,0 +1,109 @@
+package constraint_expression
+
+import (
+	"strings"
+	"testing"
+)
+
+var testClasses = map[string]string{
+	"lower":  "abcdefghijklmnopqrstuvwxyz",
+	"upper":  "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"digits": "0123456789",
+}
+
+func TestEvaluate(t *testing.T) {
+	for _, test := range []struct {
+		source   string
+		password string
+		want     bool
+	}{
+		{"count(digits) >= 2", "ab12", true},
+		{"count(digits) >= 2", "ab1", false},
+		{"count(digits) + count(upper) == 3", "A1b2", true},
+		{"length - count(lower) < 2", "abc1", true},
+		{"!contains(year) && contains(digits)", "x1999", false},
+		{"!contains(year) && contains(digits)", "x19", true},
+		{"count(sequence) == 0 || length > 10", "xAbC", false},
+		{"startsWith(upper) && endsWith(digits)", "Ab1", true},
+		{"startsWith(\"ab\") || endsWith('yz')", "xyz", true},
+		{"password == \"pass\" && password != 'word'", "pass", true},
+		{"!(count(\"a\") > 1)", "aa", false},
+		{"true && !false", "", true},
+	} {
+		expression, err := Compile(test.source, testClasses)
+		if err != nil {
+			t.Errorf("Compile(%q) failed: %v", test.source, err)
+			continue
+		}
+		if got := expression.Evaluate(test.password); got != test.want {
+			t.Errorf("%q on %q = %t, want %t", test.source, test.password, got, test.want)
+		}
+	}
+}
+
+func TestStringsMatchCaseSensitively(t *testing.T) {
+	for _, source := range []string{`count("a") >= 1`, `contains("a")`, `startsWith("a")`, `endsWith("a")`} {
+		expression, err := Compile(source, testClasses)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expression.Evaluate("ABAA") {
+			t.Errorf("%q is satisfied by ABAA", source)
+		}
+		if !expression.Evaluate("aBAa") {
+			t.Errorf("%q isn't satisfied by aBAa", source)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for source, want := range map[string]string{
+		"":                          "Unexpected end of expression",
+		"count(digits) >=":          "Unexpected end of expression",
+		"count(digits) 2":           "Unexpected \"2\" at position 14",
+		"(length > 1":               "Expected \")\" at position 11",
+		"count(digits":              "Expected \",\" or \")\" at position 12",
+		"\"open":                    "Unterminated string at position 0",
+		"length # 2":                "Unexpected character '#' at position 7",
+		"vowels > 1":                "Unknown identifier \"vowels\" at position 0",
+		"size(digits) > 1":          "Unknown function \"size\" at position 0",
+		"count(digits, upper)":      "Function \"count\" expects 1 argument, got 2",
+		"startsWith(year)":          "Function \"startsWith\" doesn't accept pattern",
+		"length":                    "Expression must evaluate to bool, got number",
+		"length && true":            "Operator \"&&\" expects bool operands, got number and bool",
+		"!length":                   "Operator \"!\" expects bool, got number",
+		"digits == upper":           "Operator \"==\" can't compare character class and character class",
+		"password > 1":              "Operator \">\" expects number operands, got string and number",
+		"count(digits) + \"a\" > 1": "Operator \"+\" expects number operands, got number and string",
+	} {
+		_, err := Compile(source, testClasses)
+		if err == nil || err.Error() != want {
+			t.Errorf("Compile(%q) = %v, want %q", source, err, want)
+		}
+	}
+}
+
+func TestCompileLimitsNesting(t *testing.T) {
+	for _, source := range []string{
+		strings.Repeat("(", maxNesting) + "true" + strings.Repeat(")", maxNesting),
+		strings.Repeat("!", maxNesting) + "true",
+		"count(" + strings.Repeat("(", maxNesting-1) + "digits" + strings.Repeat(")", maxNesting) + " > 0",
+	} {
+		if _, err := Compile(source, testClasses); err != nil {
+			t.Errorf("Compile of %d levels failed: %v", maxNesting, err)
+		}
+	}
+	for _, source := range []string{
+		strings.Repeat("(", maxNesting+1) + "true" + strings.Repeat(")", maxNesting+1),
+		strings.Repeat("!", maxNesting+1) + "true",
+		"count(" + strings.Repeat("(", maxNesting) + "digits" + strings.Repeat(")", maxNesting+1) + " > 0",
+		strings.Repeat("(", 100000) + "true" + strings.Repeat(")", 100000),
+	} {
+		if _, err := Compile(source, testClasses); err == nil || !strings.Contains(err.Error(), "nested deeper") && !strings.Contains(err.Error(), "tokens") {
+			t.Errorf("Compile of %d characters nested too deep = %v", len(source), err)
+		}
+	}
+	if _, err := Compile(strings.Repeat("length + ", maxTokens)+"1 > 0", testClasses); err == nil {
+		t.Error("Compile of a chain longer than maxTokens succeeded")
+	}
+}
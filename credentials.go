@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type CredentialsRequest struct {
+	Labels []string `json:"labels"`
+}
+
+type Credential struct {
+	Label       string       `json:"label"`
+	Password    string       `json:"password"`
+	Composition *Composition `json:"composition,omitempty"`
+}
+
+type CredentialsResponse struct {
+	Error         string                `json:"error"`
+	Credentials   []Credential          `json:"credentials"`
+	Warnings      []string              `json:"warnings,omitempty"`
+	PolicyApplied *PasswordRestrictions `json:"policyApplied,omitempty"`
+}
+
+func parseLabels(r *http.Request) ([]string, error) {
+	var request CredentialsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, bodyError(err, "Body has to be a json object with a labels array")
+	}
+	if len(request.Labels) == 0 {
+		return nil, errors.New("Parameter labels can't be empty")
+	}
+	if len(request.Labels) > config.MaxBatchCount {
+		return nil, fmt.Errorf("Parameter labels can't contain more than %d labels", config.MaxBatchCount)
+	}
+	seen := make(map[string]bool, len(request.Labels))
+	for _, label := range request.Labels {
+		if strings.TrimSpace(label) == "" {
+			return nil, errors.New("Parameter labels can't contain empty labels")
+		}
+		if config.MaxParameterLength > 0 && len(label) > config.MaxParameterLength {
+			return nil, fmt.Errorf("Labels can't be longer than %d characters", config.MaxParameterLength)
+		}
+		if seen[label] {
+			return nil, fmt.Errorf("Label %q is duplicated", label)
+		}
+		seen[label] = true
+	}
+	return request.Labels, nil
+}
+
+func writeCredentialsCSV(w http.ResponseWriter, credentials []Credential) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="credentials.csv"`)
+	w.WriteHeader(200)
+	writer := csv.NewWriter(w)
+	header := []string{"label", "password"}
+	if len(credentials) > 0 && credentials[0].Composition != nil {
+		header = append(header, compositionColumns...)
+	}
+	writer.Write(header)
+	for _, credential := range credentials {
+		row := []string{spreadsheetCell(credential.Label), credential.Password}
+		if credential.Composition != nil {
+			row = append(row, credential.Composition.columns()...)
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+}
+
+func handleCredentials(w http.ResponseWriter, r *http.Request) {
+	query, err := requestQuery(r)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	restrictions, err := parseRestrictions(query)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	composition, err := parseComposition(query)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	labels, err := parseLabels(r)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	credentials := make([]Credential, 0, len(labels))
+	for _, label := range labels {
+		password, _, err := generateWithinBudget(restrictions)
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+		credential := Credential{Label: label, Password: password}
+		if composition {
+			described := describeComposition(password)
+			credential.Composition = &described
+		}
+		credentials = append(credentials, credential)
+	}
+
+	if accepts(r, "text/csv") {
+		writeCredentialsCSV(w, credentials)
+		return
+	}
+	writeResponse(w, r, 200, CredentialsResponse{Error: "", Credentials: credentials, Warnings: restrictions.warnings, PolicyApplied: &restrictions})
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// formulaPrefixes are the first characters of cells spreadsheets evaluate as
+// formulas.
+const formulaPrefixes = "=+-@\t\r"
+
+// spreadsheetCell prefixes a cell starting like a formula with ', which
+// spreadsheets read as text and don't display, so labels sent by clients can't
+// be evaluated when the csv is opened in one. Passwords are written as issued.
+func spreadsheetCell(cell string) string {
+	if cell != "" && strings.ContainsRune(formulaPrefixes, rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// spreadsheetRow escapes the cells of a row sent by the client.
+func spreadsheetRow(row []string) []string {
+	escaped := make([]string, len(row))
+	for i, cell := range row {
+		escaped[i] = spreadsheetCell(cell)
+	}
+	return escaped
+}
+
+func csvBody(r *http.Request) (io.Reader, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, nil
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, bodyError(err, "Multipart body has to contain a csv file in the file field")
+	}
+	return file, nil
+}
+
+func rowQuery(base url.Values, header []string, row []string) url.Values {
+	query := url.Values{}
+	for key, values := range base {
+		query[key] = values
+	}
+	for i, column := range header {
+		if i < len(row) && strings.TrimSpace(row[i]) != "" {
+			query.Set(strings.TrimSpace(column), strings.TrimSpace(row[i]))
+		}
+	}
+	return query
+}
+
+func generateCSV(base url.Values, body io.Reader) ([][]string, error) {
+	composition, err := parseComposition(base)
+	if err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, bodyError(err, "Could not parse csv: "+err.Error())
+	}
+	if len(records) < 2 {
+		return nil, errors.New("Csv has to contain a header and at least one row")
+	}
+	if len(records)-1 > config.MaxBatchCount {
+		return nil, fmt.Errorf("Csv can't contain more than %d rows", config.MaxBatchCount)
+	}
+
+	header := records[0]
+	outputHeader := append(spreadsheetRow(header), "password")
+	if composition {
+		outputHeader = append(outputHeader, compositionColumns...)
+	}
+	output := [][]string{outputHeader}
+	for i, row := range records[1:] {
+		restrictions, err := parseRestrictions(rowQuery(base, header, row))
+		if err != nil {
+			return nil, fmt.Errorf("Row %d: %w", i+1, err)
+		}
+		password, _, err := generateWithinBudget(restrictions)
+		if err != nil {
+			return nil, fmt.Errorf("Row %d: %w", i+1, err)
+		}
+		row = spreadsheetRow(row)
+		for len(row) < len(header) {
+			row = append(row, "")
+		}
+		row = append(row, password)
+		if composition {
+			row = append(row, describeComposition(password).columns()...)
+		}
+		output = append(output, row)
+	}
+	return output, nil
+}
+
+func handlePasswordGenCSV(w http.ResponseWriter, r *http.Request) {
+	query, err := requestQuery(r)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	body, err := csvBody(r)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	records, err := generateCSV(query, body)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="passwords.csv"`)
+	w.WriteHeader(200)
+	writer := csv.NewWriter(w)
+	writer.WriteAll(records)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSpreadsheetCell(t *testing.T) {
+	for cell, want := range map[string]string{
+		"=SUM(A1)":  "'=SUM(A1)",
+		"+1":        "'+1",
+		"-2+3":      "'-2+3",
+		"@cmd":      "'@cmd",
+		"\tx":       "'\tx",
+		"a=b":       "a=b",
+		"'=already": "'=already",
+		"":          "",
+	} {
+		if got := spreadsheetCell(cell); got != want {
+			t.Errorf("spreadsheetCell(%q) = %q, want %q", cell, got, want)
+		}
+	}
+}
+
+func TestGenerateCSVEscapesClientColumns(t *testing.T) {
+	body := "label,prefix\n=HYPERLINK(A1),@x\nplain,-1\n"
+	records, err := generateCSV(url.Values{}, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := records[1][:2]; got[0] != "'=HYPERLINK(A1)" || got[1] != "'@x" {
+		t.Errorf("Client columns %q aren't escaped", got)
+	}
+	if password := records[1][2]; !strings.HasPrefix(password, "@x") {
+		t.Errorf("Password %q isn't the one issued", password)
+	}
+}
+
+func TestWriteCredentialsCSVEscapesFormulas(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writeCredentialsCSV(recorder, []Credential{{Label: "=cmd|' /C calc'!A0", Password: "+abc"}})
+	records, err := csv.NewReader(recorder.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := records[1]; got[0] != "'=cmd|' /C calc'!A0" {
+		t.Errorf("Label %q isn't escaped", got[0])
+	}
+	if got := records[1][1]; got != "+abc" {
+		t.Errorf("Password %q isn't the one issued", got)
+	}
+}
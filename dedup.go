@@ -0,0 +1,70 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const maxIssuedCollisions = 10
+
+type IssuedRequest struct {
+	Salt   string   `json:"salt"`
+	Hashes []string `json:"hashes"`
+}
+
+// issuedPasswords are the hex encoded sha256(salt + password) hashes of
+// passwords which must not be issued again, extended with every password
+// of the batch as it's generated.
+type issuedPasswords struct {
+	salt   string
+	hashes map[string]bool
+}
+
+func parseIssued(r *http.Request) (*issuedPasswords, error) {
+	var request IssuedRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, bodyError(err, "Body has to be a json object with a salt and a hashes array")
+	}
+	issued := &issuedPasswords{salt: request.Salt, hashes: make(map[string]bool, len(request.Hashes))}
+	for _, hash := range request.Hashes {
+		hash = strings.ToLower(hash)
+		if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+			return nil, errors.New("Parameter hashes can only contain hex encoded sha256 hashes")
+		}
+		issued.hashes[hash] = true
+	}
+	return issued, nil
+}
+
+func (issued *issuedPasswords) hash(password string) string {
+	sum := sha256.Sum256([]byte(issued.salt + password))
+	return hex.EncodeToString(sum[:])
+}
+
+// claim records password as issued unless it already was.
+func (issued *issuedPasswords) claim(password string) bool {
+	hash := issued.hash(password)
+	if issued.hashes[hash] {
+		return false
+	}
+	issued.hashes[hash] = true
+	return true
+}
+
+// generateUnissued generates passwords until one wasn't issued yet.
+func generateUnissued(restrictions PasswordRestrictions, issued *issuedPasswords) (string, error) {
+	for i := 0; ; i++ {
+		password, _, err := generateWithinBudget(restrictions)
+		if err != nil || issued == nil || issued.claim(password) {
+			return password, err
+		}
+		constraintViolations.Add("issued", 1)
+		if i == maxIssuedCollisions {
+			return "", errors.New("Generated passwords keep colliding with issued ones, loosen the restrictions to allow more passwords")
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"sort"
+)
+
+type Rejection struct {
+	Constraint string `json:"constraint"`
+	Rejected   int    `json:"rejected"`
+}
+
+// Diagnosis tells which constraints rejected the candidates of a password
+// that couldn't be generated, the one rejecting the most first, so that
+// contradictory restrictions can be fixed instead of retried.
+type Diagnosis struct {
+	Attempts   int         `json:"attempts"`
+	Rejections []Rejection `json:"rejections"`
+}
+
+// rejections counts the rejected candidates of a password by the
+// constraint_violations reason.
+type rejections map[string]int
+
+func (r rejections) diagnose(attempts int) *Diagnosis {
+	diagnosis := &Diagnosis{Attempts: attempts, Rejections: []Rejection{}}
+	for constraint, rejected := range r {
+		diagnosis.Rejections = append(diagnosis.Rejections, Rejection{Constraint: constraint, Rejected: rejected})
+	}
+	sort.Slice(diagnosis.Rejections, func(i, j int) bool {
+		a, b := diagnosis.Rejections[i], diagnosis.Rejections[j]
+		return a.Rejected > b.Rejected || (a.Rejected == b.Rejected && a.Constraint < b.Constraint)
+	})
+	return diagnosis
+}
+
+type exhaustedError struct {
+	err       error
+	diagnosis *Diagnosis
+}
+
+func (e exhaustedError) Error() string {
+	return e.err.Error()
+}
+
+func (e exhaustedError) Unwrap() error {
+	return e.err
+}
+
+func diagnosisOf(err error) *Diagnosis {
+	var exhausted exhaustedError
+	if errors.As(err, &exhausted) {
+		return exhausted.diagnosis
+	}
+	return nil
+}
+
+func errorResponse(err error) Response {
+	return Response{Error: err.Error(), Password: "", Diagnosis: diagnosisOf(err)}
+}
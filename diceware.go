@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"password_gen/passphrase"
+)
+
+const defaultDicewareWords = 6
+
+func passphraseMode(restrictions *PasswordRestrictions, query url.Values) (*passphrase.Pattern, error) {
+	if restrictions.Words == 0 {
+		restrictions.Words = defaultDicewareWords
+		restrictions.warn(fmt.Sprintf("Parameter words defaulted to %d", defaultDicewareWords))
+	}
+	if !query.Has("separator") {
+		restrictions.Separator = "-"
+	}
+	pattern, err := passphrase.Diceware(restrictions.Words, restrictions.Separator, restrictions.Capitalize)
+	if err != nil {
+		return nil, fmt.Errorf("Parameter words is invalid: %w", err)
+	}
+	return &pattern, nil
+}
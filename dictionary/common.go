@@ -0,0 +1,42 @@
+package dictionary
+
+import (
+	"unicode/utf8"
+
+	"github.com/nbutton23/zxcvbn-go/frequency"
+)
+
+// CommonWordsTopN is how many of the most frequent english words, common
+// passwords and names are common words.
+const CommonWordsTopN = 10000
+
+// Words like monkey or michael are only in the passwords and names lists of
+// zxcvbn, which keeps every word in one list.
+var commonWords, maxCommonWordLength = rankWords(
+	topWords(frequency.Lists["English"].List, CommonWordsTopN),
+	topWords(frequency.Lists["Passwords"].List, CommonWordsTopN),
+	topWords(frequency.Lists["FemaleNames"].List, CommonWordsTopN),
+	topWords(frequency.Lists["MaleNames"].List, CommonWordsTopN),
+	topWords(frequency.Lists["Surname"].List, CommonWordsTopN),
+)
+
+func topWords(words []string, n int) []string {
+	return words[:min(n, len(words))]
+}
+
+// CommonWord is the first common word of at least minLength
+// letters found in password, ignoring case and reading leet substitutes as
+// letters, e.g. house in H0use12.
+func CommonWord(password string, minLength int) (string, bool) {
+	runes := []rune(password)
+	for start := range runes {
+		for end := start + minLength; end <= len(runes) && end-start <= maxCommonWordLength; end++ {
+			for _, variant := range variants(runes[start:end]) {
+				if _, ok := commonWords[variant]; ok && utf8.RuneCountInString(variant) >= minLength {
+					return variant, true
+				}
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,152 @@
+package dictionary
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	CrackTopN        = 10000
+	maxAffixLength   = 4
+	minCrackedLength = 3
+)
+
+type Crack struct {
+	Word string `json:"word"`
+	Rank int    `json:"rank"`
+	Rule string `json:"rule"`
+}
+
+func isAffix(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func caseRule(core string) (string, bool) {
+	runes := []rune(core)
+	first, rest := string(runes[:1]), string(runes[1:])
+	switch {
+	case core == strings.ToLower(core):
+		return "", true
+	case first == strings.ToUpper(first) && rest == strings.ToLower(rest):
+		return "c", true
+	case core == strings.ToUpper(core):
+		return "u", true
+	case first == strings.ToLower(first) && rest == strings.ToUpper(rest):
+		return "C", true
+	}
+	return "", false
+}
+
+func substitutionRules(token string, word string) []string {
+	var rules []string
+	seen := map[string]bool{}
+	wordRunes := []rune(word)
+	for i, r := range []rune(token) {
+		if _, ok := leet[r]; !ok || i >= len(wordRunes) {
+			continue
+		}
+		rule := "s" + string(wordRunes[i]) + string(r)
+		if !seen[rule] {
+			seen[rule] = true
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func affixRules(op string, affix string, reversed bool) []string {
+	runes := []rune(affix)
+	rules := make([]string, 0, len(runes))
+	for i := range runes {
+		r := runes[i]
+		if reversed {
+			r = runes[len(runes)-1-i]
+		}
+		rules = append(rules, op+string(r))
+	}
+	return rules
+}
+
+// cores undoes the word-level rules, reverse and duplicate, of a core.
+func cores(core string) map[string]string {
+	candidates := map[string]string{core: ""}
+	runes := []rune(core)
+	reversed := make([]rune, len(runes))
+	for i, r := range runes {
+		reversed[len(runes)-1-i] = r
+	}
+	candidates[string(reversed)] = "r"
+	if half := len(runes) / 2; len(runes)%2 == 0 && string(runes[:half]) == string(runes[half:]) {
+		candidates[string(runes[:half])] = "d"
+	}
+	return candidates
+}
+
+func crackCore(core string, topN int) (Crack, bool) {
+	var best Crack
+	found := false
+	caseOp, ok := caseRule(core)
+	if !ok || len([]rune(core)) > 2*maxWordLength {
+		return best, false
+	}
+	for candidate, wordOp := range cores(core) {
+		if len([]rune(candidate)) < minCrackedLength {
+			continue
+		}
+		for _, variant := range variants([]rune(candidate)) {
+			rank, ok := ranks[variant]
+			if !ok || rank > topN || (found && rank >= best.Rank) {
+				continue
+			}
+			rules := []string{}
+			if caseOp != "" {
+				rules = append(rules, caseOp)
+			}
+			rules = append(rules, substitutionRules(strings.ToLower(candidate), variant)...)
+			if wordOp != "" {
+				rules = append(rules, wordOp)
+			}
+			best = Crack{Word: variant, Rank: rank, Rule: strings.Join(rules, " ")}
+			found = true
+		}
+	}
+	return best, found
+}
+
+// Crackability reports the cheapest way to produce password from the topN
+// most frequent words with the rules of common hashcat rule sets, or nil
+// when those rules don't reach it.
+func Crackability(password string, topN int) *Crack {
+	runes := []rune(password)
+	var best *Crack
+	for prefixLength := 0; prefixLength <= maxAffixLength && prefixLength < len(runes); prefixLength++ {
+		for suffixLength := 0; suffixLength <= maxAffixLength && prefixLength+suffixLength < len(runes); suffixLength++ {
+			prefix := string(runes[:prefixLength])
+			suffix := string(runes[len(runes)-suffixLength:])
+			if !isAffix(prefix) || !isAffix(suffix) {
+				continue
+			}
+			crack, ok := crackCore(string(runes[prefixLength:len(runes)-suffixLength]), topN)
+			if !ok || (best != nil && crack.Rank >= best.Rank) {
+				continue
+			}
+			rules := []string{}
+			if crack.Rule != "" {
+				rules = append(rules, crack.Rule)
+			}
+			rules = append(rules, affixRules("^", prefix, true)...)
+			rules = append(rules, affixRules("$", suffix, false)...)
+			if len(rules) == 0 {
+				rules = append(rules, ":")
+			}
+			crack.Rule = strings.Join(rules, " ")
+			best = &crack
+		}
+	}
+	return best
+}
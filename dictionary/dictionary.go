@@ -0,0 +1,263 @@
+package dictionary
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/nbutton23/zxcvbn-go/frequency"
+)
+
+const (
+	minWordLength = 3
+	maxVariants   = 64
+)
+
+type Match struct {
+	Word             string  `json:"word"`
+	Token            string  `json:"token"`
+	Start            int     `json:"start"`
+	Rank             int     `json:"rank"`
+	Leet             bool    `json:"leet"`
+	EntropyReduction float64 `json:"entropyReduction"`
+}
+
+type Analysis struct {
+	Matches              []Match `json:"matches"`
+	EntropyBits          float64 `json:"entropyBits"`
+	EffectiveEntropyBits float64 `json:"effectiveEntropyBits"`
+}
+
+var leet = map[rune][]rune{
+	'4': {'a'}, '@': {'a'}, '8': {'b'}, '(': {'c'}, '{': {'c'}, '3': {'e'},
+	'6': {'g'}, '9': {'g'}, '1': {'i', 'l'}, '!': {'i'}, '|': {'i', 'l'},
+	'0': {'o'}, '$': {'s'}, '5': {'s'}, '7': {'t'}, '+': {'t'}, '2': {'z'}, '%': {'x'},
+}
+
+// zxcvbn keeps every word in only one of its lists, so the english words
+// which are also common passwords or names are ranked from those lists.
+var ranks, maxWordLength = rankWords(
+	frequency.Lists["English"].List,
+	frequency.Lists["Passwords"].List,
+	frequency.Lists["FemaleNames"].List,
+	frequency.Lists["MaleNames"].List,
+	frequency.Lists["Surname"].List,
+)
+
+func isWord(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func rankWords(lists ...[]string) (map[string]int, int) {
+	ranked := make(map[string]int)
+	longest := 0
+	for _, words := range lists {
+		for i, word := range words {
+			if _, ok := ranked[word]; ok || len([]rune(word)) < minWordLength || !isWord(word) {
+				continue
+			}
+			ranked[word] = i + 1
+			if l := len([]rune(word)); l > longest {
+				longest = l
+			}
+		}
+	}
+	return ranked, longest
+}
+
+// Unleet reads the leet substitutes in s as their letters, the first one for
+// substitutes standing for several, like 1 for i and l.
+func Unleet(s string) string {
+	return strings.Map(func(r rune) rune {
+		if letters, ok := leet[r]; ok {
+			return letters[0]
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
+
+// ContainsLeet reports whether s contains word, ignoring case and reading
+// leet substitutes as any of the letters they stand for, so p4ssw0rd and
+// pa55w0rd both contain password and 1|ly contains lily.
+func ContainsLeet(s string, word string) bool {
+	runes, letters := []rune(s), []rune(strings.ToLower(word))
+	for start := 0; start+len(letters) <= len(runes); start++ {
+		matched := true
+		for i, letter := range letters {
+			r := runes[start+i]
+			if unicode.ToLower(r) != letter && !slices.Contains(leet[r], letter) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func variants(token []rune) []string {
+	results := []string{""}
+	for _, r := range token {
+		options := []rune{unicode.ToLower(r)}
+		if substitutes, ok := leet[r]; ok {
+			options = substitutes
+		}
+		next := make([]string, 0, len(results)*len(options))
+		for _, prefix := range results {
+			for _, option := range options {
+				next = append(next, prefix+string(option))
+			}
+		}
+		if len(next) > maxVariants {
+			next = next[:maxVariants]
+		}
+		results = next
+	}
+	return results
+}
+
+func charsetSize(password string) int {
+	var lower, upper, digit, special, other bool
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII && unicode.IsPrint(r):
+			special = true
+		default:
+			other = true
+		}
+	}
+	size := 0
+	for _, class := range []struct {
+		present bool
+		size    int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {special, 33}, {other, 100}} {
+		if class.present {
+			size += class.size
+		}
+	}
+	return size
+}
+
+func caseEntropy(token string) float64 {
+	first := string([]rune(token)[:1])
+	rest := strings.TrimPrefix(token, first)
+	switch {
+	case token == strings.ToLower(token):
+		return 0
+	case token == strings.ToUpper(token):
+		return 1
+	case first == strings.ToUpper(first) && rest == strings.ToLower(rest):
+		return 1
+	default:
+		letters := 0
+		for _, r := range token {
+			if unicode.IsLetter(r) {
+				letters++
+			}
+		}
+		return float64(letters)
+	}
+}
+
+func leetEntropy(token []rune) (float64, bool) {
+	substitutions := 0
+	for _, r := range token {
+		if _, ok := leet[r]; ok {
+			substitutions++
+		}
+	}
+	return float64(substitutions), substitutions > 0
+}
+
+func findMatches(password string) []Match {
+	runes := []rune(password)
+	var candidates []Match
+	for start := range runes {
+		for end := start + minWordLength; end <= len(runes) && end-start <= maxWordLength; end++ {
+			token := runes[start:end]
+			best := 0
+			word := ""
+			for _, variant := range variants(token) {
+				if rank, ok := ranks[variant]; ok && (best == 0 || rank < best) {
+					best, word = rank, variant
+				}
+			}
+			if best == 0 {
+				continue
+			}
+			_, isLeet := leetEntropy(token)
+			candidates = append(candidates, Match{Word: word, Token: string(token), Start: start, Rank: best, Leet: isLeet})
+		}
+	}
+
+	return coveringMatches(candidates, len(runes))
+}
+
+// coveringMatches picks the non-overlapping matches covering the most
+// characters, preferring fewer and so longer words.
+func coveringMatches(candidates []Match, length int) []Match {
+	byStart := make([][]Match, length)
+	for _, candidate := range candidates {
+		byStart[candidate.Start] = append(byStart[candidate.Start], candidate)
+	}
+	covered := make([]int, length+1)
+	count := make([]int, length+1)
+	chosen := make([]*Match, length)
+	for i := length - 1; i >= 0; i-- {
+		covered[i], count[i] = covered[i+1], count[i+1]
+		for j := range byStart[i] {
+			candidate := &byStart[i][j]
+			end := i + len([]rune(candidate.Token))
+			c, n := end-i+covered[end], 1+count[end]
+			if c > covered[i] || (c == covered[i] && chosen[i] != nil && n < count[i]) {
+				covered[i], count[i] = c, n
+				chosen[i] = candidate
+			}
+		}
+	}
+	var matches []Match
+	for i := 0; i < length; {
+		if chosen[i] == nil || covered[i] == covered[i+1] {
+			i++
+			continue
+		}
+		matches = append(matches, *chosen[i])
+		i += len([]rune(chosen[i].Token))
+	}
+	return matches
+}
+
+func Analyze(password string) Analysis {
+	bitsPerChar := 0.0
+	if size := charsetSize(password); size > 0 {
+		bitsPerChar = math.Log2(float64(size))
+	}
+	analysis := Analysis{
+		Matches:     []Match{},
+		EntropyBits: float64(len([]rune(password))) * bitsPerChar,
+	}
+	analysis.EffectiveEntropyBits = analysis.EntropyBits
+	for _, match := range findMatches(password) {
+		token := []rune(match.Token)
+		leetBits, _ := leetEntropy(token)
+		dictionaryBits := math.Log2(float64(match.Rank)) + caseEntropy(match.Token) + leetBits
+		match.EntropyReduction = math.Max(0, float64(len(token))*bitsPerChar-dictionaryBits)
+		analysis.EffectiveEntropyBits -= match.EntropyReduction
+		analysis.Matches = append(analysis.Matches, match)
+	}
+	return analysis
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"password_gen/dictionary"
+)
+
+const (
+	// minDictionaryWordLength is the length of the shortest word
+	// rejectDictionaryWords rejects, shorter words appear by chance.
+	minDictionaryWordLength = 4
+	// dictionaryAttempts is the least number of candidates generated with
+	// rejectDictionaryWords, readable candidates often containing a word.
+	dictionaryAttempts = 100
+)
+
+func resolveRejectDictionaryWords(restrictions *PasswordRestrictions) {
+	if !restrictions.RejectDictionaryWords {
+		return
+	}
+	reason := ""
+	switch {
+	case restrictions.Mode == "passphrase", restrictions.Mode == "memorable":
+		reason = "mode is " + restrictions.Mode
+	case restrictions.PassphrasePattern != "":
+		reason = "passphrasePattern is set"
+	}
+	if reason != "" {
+		restrictions.warn("Parameter rejectDictionaryWords ignored because " + reason)
+		restrictions.RejectDictionaryWords = false
+	}
+}
+
+func checkDictionaryWords(password string) error {
+	if _, found := dictionary.CommonWord(password, minDictionaryWordLength); found {
+		return violation("dictionaryWords", errors.New("Generated password contains a dictionary word, try again"))
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type DryRun struct {
+	Strategy    string   `json:"strategy"`
+	EntropyBits *float64 `json:"entropyBits,omitempty"`
+	MinLength   int      `json:"minLength"`
+	MaxLength   int      `json:"maxLength"`
+}
+
+func parseDryRun(query url.Values) (bool, error) {
+	if query.Get("dryRun") == "" {
+		return false, nil
+	}
+	dryRun, err := strconv.ParseBool(query.Get("dryRun"))
+	if err != nil {
+		return false, errors.New("Parameter dryRun has to be true or false")
+	}
+	return dryRun, nil
+}
+
+// estimate describes the passwords restrictions generate without generating
+// one. Readable passwords have no entropy estimate, as their characters
+// aren't drawn uniformly.
+func estimate(restrictions PasswordRestrictions) DryRun {
+	dryRun := DryRun{Strategy: strategyName(restrictions)}
+	bits := 0.0
+	switch dryRun.Strategy {
+	case "pattern":
+		for _, slot := range restrictions.pattern {
+			if slot.charset != "" {
+				bits += math.Log2(float64(len(slot.charset)))
+			}
+		}
+		dryRun.MinLength, dryRun.MaxLength = len(restrictions.pattern), len(restrictions.pattern)
+	case "hex", "base64":
+		bits = float64(restrictions.Bytes) * restrictions.entropyPerUnit()
+		length := len(encodeSecret(make([]byte, restrictions.Bytes), restrictions.Mode))
+		dryRun.MinLength, dryRun.MaxLength = length, length
+	case "pin":
+		bits = float64(restrictions.PinLength) * restrictions.entropyPerUnit()
+		dryRun.MinLength, dryRun.MaxLength = restrictions.PinLength, restrictions.PinLength
+	case "memorable":
+		words := restrictions.words
+		if restrictions.WordSource == "markov" {
+			// Only the lengths of markov words are known, their
+			// entropy isn't estimated like for readable passwords.
+			words = []string{strings.Repeat("x", minMarkovWordLength), strings.Repeat("x", maxMarkovWordLength)}
+		}
+		bits, dryRun.MinLength, dryRun.MaxLength = restrictions.passphrasePattern.Estimate(words, restrictions.charset(Digits), restrictions.charset(restrictions.specials()))
+	case "passphrase", "passphrasePattern":
+		bits, dryRun.MinLength, dryRun.MaxLength = restrictions.passphrasePattern.Estimate(restrictions.words, restrictions.charset(Digits), restrictions.charset(restrictions.specials()))
+	case "random":
+		length := restrictions.MaxLength
+		if restrictions.MaxCharOccurrences > 0 {
+			length = min(length, restrictions.repeatCapacity())
+		}
+		length = max(length, restrictions.MinLength)
+		bits = float64(length-len(restrictions.Prefix)-len(restrictions.Suffix)) * restrictions.entropyPerUnit()
+		dryRun.MinLength, dryRun.MaxLength = length, length
+	default:
+		dryRun.MinLength, dryRun.MaxLength = restrictions.MinLength, restrictions.MaxLength
+	}
+	if dryRun.Strategy != "readable" && dryRun.Strategy != "syllable" && restrictions.WordSource != "markov" {
+		bits = math.Round(bits*100) / 100
+		dryRun.EntropyBits = &bits
+	}
+	dryRun.MinLength = len(restrictions.format(strings.Repeat("x", dryRun.MinLength)))
+	dryRun.MaxLength = len(restrictions.format(strings.Repeat("x", dryRun.MaxLength)))
+	return dryRun
+}
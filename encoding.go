@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type responseEncoding struct {
+	contentType string
+	encode      func(w io.Writer, v any) error
+}
+
+var jsonEncoding = responseEncoding{
+	contentType: "application/json",
+	encode: func(w io.Writer, v any) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(v)
+	},
+}
+
+var responseEncodings = []responseEncoding{
+	jsonEncoding,
+	{
+		contentType: "application/msgpack",
+		encode: func(w io.Writer, v any) error {
+			encoder := msgpack.NewEncoder(w)
+			encoder.SetCustomStructTag("json")
+			return encoder.Encode(v)
+		},
+	},
+	{
+		contentType: "application/cbor",
+		encode: func(w io.Writer, v any) error {
+			return cbor.NewEncoder(w).Encode(v)
+		},
+	},
+}
+
+func acceptedMediaTypes(r *http.Request) []string {
+	var mediaTypes []string
+	for _, accepted := range strings.Split(strings.Join(r.Header.Values("Accept"), ","), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err == nil {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+	}
+	return mediaTypes
+}
+
+func accepts(r *http.Request, contentType string) bool {
+	for _, mediaType := range acceptedMediaTypes(r) {
+		if mediaType == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+func negotiateEncoding(r *http.Request) responseEncoding {
+	for _, mediaType := range acceptedMediaTypes(r) {
+		if mediaType == "application/x-msgpack" {
+			mediaType = "application/msgpack"
+		}
+		for _, encoding := range responseEncodings {
+			if encoding.contentType == mediaType {
+				return encoding
+			}
+		}
+	}
+	return jsonEncoding
+}
+
+func writeResponse(w http.ResponseWriter, r *http.Request, status int, v any) {
+	status, v = responseFields(r, status, v)
+	encoding := negotiateEncoding(r)
+	w.Header().Set("Content-Type", encoding.contentType)
+	w.WriteHeader(status)
+	encoding.encode(w, v)
+}
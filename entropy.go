@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"password_gen/passphrase"
+	"strings"
+	"unicode/utf8"
+)
+
+// entropyPerUnit is the entropy in bits of a single character, digit or
+// word of the strategies which can be sized for entropyBits.
+func (restrictions PasswordRestrictions) entropyPerUnit() float64 {
+	switch {
+	case restrictions.Mode == "passphrase":
+		if restrictions.words != nil {
+			return math.Log2(float64(len(restrictions.words)))
+		}
+		return math.Log2(float64(passphrase.WordlistSize()))
+	case isSecretMode(restrictions.Mode):
+		return 8
+	case restrictions.Mode == "pin":
+		choices := len(restrictions.charset(Digits))
+		if restrictions.NoRepeatedDigits {
+			choices--
+		}
+		if restrictions.NoDigitSequences {
+			choices--
+		}
+		return math.Log2(math.Max(float64(choices), 1))
+	case restrictions.Balanced:
+		// Conservatively the average of the classes, ignoring the
+		// arrangement of the classes.
+		classes := restrictions.balancedClasses()
+		bits := 0.0
+		for _, class := range classes {
+			bits += math.Log2(float64(utf8.RuneCountInString(class)))
+		}
+		return bits / math.Max(float64(len(classes)), 1)
+	case restrictions.weights != nil:
+		return restrictions.weightedEntropy()
+	default:
+		return math.Log2(math.Max(float64(utf8.RuneCountInString(restrictions.baseCharset())), 1))
+	}
+}
+
+// sizeForEntropy sets the length, pinLength or words needed to reach
+// entropyBits with the active character set or wordlist.
+func (c Config) sizeForEntropy(restrictions *PasswordRestrictions, query url.Values) error {
+	if restrictions.EntropyBits == 0 {
+		return nil
+	}
+	if restrictions.EntropyBits < 0 {
+		return fmt.Errorf("Parameter entropyBits (%g) can't be negative", restrictions.EntropyBits)
+	}
+	if restrictions.UserReadable || restrictions.Pattern != "" || restrictions.PassphrasePattern != "" || restrictions.Mode == "memorable" {
+		return errors.New("Parameter entropyBits can only be combined with random passwords, mode=pin, mode=passphrase, mode=hex and mode=base64")
+	}
+	perUnit := restrictions.entropyPerUnit()
+	if perUnit == 0 {
+		return errors.New("Parameter entropyBits can't be reached because only one character or word is allowed")
+	}
+	units := int(math.Ceil(restrictions.EntropyBits / perUnit))
+
+	switch restrictions.Mode {
+	case "passphrase":
+		if query.Has("words") {
+			return errors.New("Parameters entropyBits and words can't be combined")
+		}
+		restrictions.Words = units
+	case "pin":
+		if query.Has("pinLength") {
+			return errors.New("Parameters entropyBits and pinLength can't be combined")
+		}
+		restrictions.PinLength = units
+	case "hex", "base64":
+		if query.Has("bytes") {
+			return errors.New("Parameters entropyBits and bytes can't be combined")
+		}
+		restrictions.Bytes = units
+	default:
+		units += len(restrictions.Prefix) + len(restrictions.Suffix)
+		if query.Has("maxLength") {
+			if restrictions.MaxLength < units {
+				return fmt.Errorf("Parameter entropyBits (%g) needs %d characters, but maxLength is %d", restrictions.EntropyBits, units, restrictions.MaxLength)
+			}
+		} else {
+			if c.MaxLengthCap > 0 && units > c.MaxLengthCap {
+				return fmt.Errorf("Parameter entropyBits (%g) needs %d characters, more than the server maximum of %d", restrictions.EntropyBits, units, c.MaxLengthCap)
+			}
+			restrictions.MaxLength = max(units, restrictions.MinLength)
+			restrictions.dropWarning("Parameter maxLength defaulted")
+			restrictions.warn(fmt.Sprintf("Parameter maxLength set to %d to reach entropyBits (%g)", restrictions.MaxLength, restrictions.EntropyBits))
+		}
+		restrictions.MinLength = max(restrictions.MinLength, units)
+	}
+	return nil
+}
+
+// enforceEntropyFloor lengthens random passwords, PINs and passphrases
+// whose estimated entropy is below minEntropyBits, e.g. because exclusions
+// shrunk the alphabet.
+func (c Config) enforceEntropyFloor(restrictions *PasswordRestrictions) error {
+	if c.MinEntropyBits == 0 || restrictions.UserReadable || restrictions.Pattern != "" || restrictions.PassphrasePattern != "" || restrictions.Mode == "memorable" {
+		return nil
+	}
+	perUnit := restrictions.entropyPerUnit()
+	if perUnit == 0 {
+		return fmt.Errorf("Restrictions allow only one character or word, which can't reach the server minimum entropy of %g bits", c.MinEntropyBits)
+	}
+	units := int(math.Ceil(c.MinEntropyBits / perUnit))
+
+	switch restrictions.Mode {
+	case "passphrase":
+		words := restrictions.Words
+		if words == 0 {
+			words = defaultDicewareWords
+		}
+		if words < units {
+			restrictions.Words = units
+			restrictions.warn(fmt.Sprintf("Parameter words raised to %d to reach the server minimum entropy of %g bits", units, c.MinEntropyBits))
+		}
+	case "pin":
+		length := restrictions.PinLength
+		if length == 0 {
+			length = defaultPinLength
+		}
+		if length < units {
+			restrictions.PinLength = units
+			restrictions.warn(fmt.Sprintf("Parameter pinLength raised to %d to reach the server minimum entropy of %g bits", units, c.MinEntropyBits))
+		}
+	case "hex", "base64":
+		bytes := restrictions.Bytes
+		if bytes == 0 {
+			bytes = defaultSecretBytes
+		}
+		if bytes < units {
+			restrictions.Bytes = units
+			restrictions.warn(fmt.Sprintf("Parameter bytes raised to %d to reach the server minimum entropy of %g bits", units, c.MinEntropyBits))
+		}
+	default:
+		units += len(restrictions.Prefix) + len(restrictions.Suffix)
+		if restrictions.MaxLength < units {
+			if c.MaxLengthCap > 0 && units > c.MaxLengthCap {
+				return fmt.Errorf("Restrictions need %d characters to reach the server minimum entropy of %g bits, more than the server maximum of %d", units, c.MinEntropyBits, c.MaxLengthCap)
+			}
+			restrictions.MaxLength = units
+			restrictions.dropWarning("Parameter maxLength defaulted")
+			restrictions.warn(fmt.Sprintf("Parameter maxLength raised to %d to reach the server minimum entropy of %g bits", units, c.MinEntropyBits))
+		}
+		if restrictions.MaxCharOccurrences > 0 && restrictions.repeatCapacity() < units {
+			return fmt.Errorf("Restrictions need %d characters to reach the server minimum entropy of %g bits, but maxCharOccurrences (%d) allows only %d", units, c.MinEntropyBits, restrictions.MaxCharOccurrences, restrictions.repeatCapacity())
+		}
+	}
+	return nil
+}
+
+func (restrictions *PasswordRestrictions) dropWarning(prefix string) {
+	kept := restrictions.warnings[:0]
+	for _, warning := range restrictions.warnings {
+		if !strings.HasPrefix(warning, prefix) {
+			kept = append(kept, warning)
+		}
+	}
+	restrictions.warnings = kept
+}
+
+// achievedEntropy is the entropy of password under the strategy it was
+// generated with, as sized by sizeForEntropy.
+func achievedEntropy(password string, restrictions PasswordRestrictions) float64 {
+	units := utf8.RuneCountInString(password) - len(restrictions.Prefix) - len(restrictions.Suffix)
+	if restrictions.Mode == "passphrase" {
+		units = restrictions.Words
+	}
+	if isSecretMode(restrictions.Mode) {
+		units = restrictions.Bytes
+	}
+	return math.Round(float64(units)*restrictions.entropyPerUnit()*100) / 100
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultEventInterval = time.Second
+	minEventInterval     = 100 * time.Millisecond
+)
+
+func parseEventInterval(query url.Values) (time.Duration, error) {
+	if query.Get("interval") == "" {
+		return defaultEventInterval, nil
+	}
+	ms, err := strconv.Atoi(query.Get("interval"))
+	if err != nil || time.Duration(ms)*time.Millisecond < minEventInterval {
+		return 0, fmt.Errorf("Parameter interval has to be a number of milliseconds not smaller than %d", minEventInterval.Milliseconds())
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+func writeEvent(w http.ResponseWriter, flusher http.Flusher, event string, response Response) {
+	data, _ := json.Marshal(response)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	flusher.Flush()
+}
+
+func handlePasswordGenEvents(w http.ResponseWriter, r *http.Request) {
+	query, err := requestQuery(r)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	interval, err := parseEventInterval(query)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	restrictions, err := parseRestrictions(query)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		handleError(w, r, errors.New("Streaming is not supported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(200)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		password, metadata, err := generateWithinBudget(restrictions)
+		if err != nil {
+			writeEvent(w, flusher, "error", errorResponse(err))
+			return
+		}
+		writeEvent(w, flusher, "password", Response{Error: "", Password: password, Warnings: restrictions.warnings, Metadata: &metadata, PolicyApplied: &restrictions})
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import "strings"
+
+const AmbiguousChars = "Il1|O0o"
+
+func (restrictions PasswordRestrictions) excluded() string {
+	excluded := restrictions.ExcludeChars
+	if restrictions.ExcludeAmbiguous {
+		excluded += AmbiguousChars
+	}
+	if restrictions.spellable() {
+		excluded += UnspellableChars
+	}
+	if restrictions.GroupSize > 0 {
+		excluded += restrictions.GroupSeparator
+	}
+	return excluded
+}
+
+func (restrictions PasswordRestrictions) charset(group string) string {
+	excluded := restrictions.excluded()
+	if excluded == "" && restrictions.AllowedChars == "" {
+		return group
+	}
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(excluded, r) {
+			return -1
+		}
+		if restrictions.AllowedChars != "" && !strings.ContainsRune(restrictions.AllowedChars, r) {
+			return -1
+		}
+		return r
+	}, group)
+}
+
+func (restrictions PasswordRestrictions) baseCharset() string {
+	if restrictions.AllowedChars != "" {
+		return restrictions.charset(uniqueChars(restrictions.AllowedChars))
+	}
+	return restrictions.charset(restrictions.lower() + Digits + restrictions.specials() + restrictions.unicodeChars())
+}
+
+func (restrictions PasswordRestrictions) allowed(password string) bool {
+	if restrictions.AllowedChars == "" {
+		return true
+	}
+	for _, r := range password {
+		if !strings.ContainsRune(restrictions.AllowedChars, r) {
+			return false
+		}
+	}
+	return true
+}
+
+func uniqueChars(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if !strings.ContainsRune(sb.String(), r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func validAllowedChars(s string) bool {
+	for _, r := range s {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
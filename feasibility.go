@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"password_gen/syllable"
+	"strings"
+	"unicode/utf8"
+)
+
+type groupRequirement struct {
+	parameter string
+	count     int
+	charset   string
+	counts    string
+}
+
+func groupRequirements(restrictions PasswordRestrictions) []groupRequirement {
+	return []groupRequirement{
+		{parameter: "minDigits", count: restrictions.MinDigits, charset: restrictions.charset(Digits), counts: Digits},
+		{parameter: "minSpecialChars", count: restrictions.MinSpecialChars, charset: restrictions.charset(restrictions.specials()), counts: restrictions.countedSpecials()},
+		{parameter: "minLetters", count: restrictions.MinLetters, charset: restrictions.charset(restrictions.lower()), counts: restrictions.lower() + restrictions.upper()},
+		{parameter: "minUpperCase", count: restrictions.MinUpperCase, charset: restrictions.charset(restrictions.upper()), counts: restrictions.upper()},
+		{parameter: "minLowerCase", count: restrictions.MinLowerCase, charset: restrictions.charset(restrictions.lower()), counts: restrictions.lower()},
+	}
+}
+
+func checkFeasibility(restrictions PasswordRestrictions) error {
+	var problems []string
+	requirements := groupRequirements(restrictions)
+
+	for _, parameter := range []struct {
+		name  string
+		value int
+	}{
+		{"minLength", restrictions.MinLength},
+		{"maxLength", restrictions.MaxLength},
+		{"classSpacing", restrictions.ClassSpacing},
+		{"minSyllables", restrictions.MinSyllables},
+		{"maxSyllables", restrictions.MaxSyllables},
+		{"maxCharOccurrences", restrictions.MaxCharOccurrences},
+		{"maxConsecutiveIdentical", restrictions.MaxConsecutiveIdentical},
+		{"minWords", restrictions.MinWords},
+	} {
+		if parameter.value < 0 {
+			problems = append(problems, fmt.Sprintf("%s (%d) can't be negative", parameter.name, parameter.value))
+		}
+	}
+	for _, requirement := range requirements {
+		if requirement.count < 0 {
+			problems = append(problems, fmt.Sprintf("%s (%d) can't be negative", requirement.parameter, requirement.count))
+		}
+	}
+	if (restrictions.Mode == "passphrase" || restrictions.Mode == "memorable") && strings.ContainsAny(restrictions.Separator, restrictions.excluded()) {
+		problems = append(problems, fmt.Sprintf("separator %q contains characters excluded by excludeChars or excludeAmbiguous", restrictions.Separator))
+	}
+	if restrictions.Mode == "memorable" {
+		problems = append(problems, memorableProblems(restrictions)...)
+	}
+	if restrictions.Mode == "pin" {
+		if digits := restrictions.charset(Digits); digits == "" {
+			problems = append(problems, "pin can't be generated because no digits are allowed")
+		} else if restrictions.NoRepeatedDigits && len(digits) < 2 {
+			problems = append(problems, "noRepeatedDigits needs at least 2 allowed digits")
+		}
+	}
+	if len(problems) > 0 || restrictions.passphrasePattern != nil || restrictions.Mode == "pin" || isSecretMode(restrictions.Mode) || restrictions.pattern != nil {
+		return feasibilityError(problems)
+	}
+
+	problems = append(problems, groupCapProblems(restrictions)...)
+	problems = append(problems, anchorProblems(restrictions)...)
+	problems = append(problems, affixProblems(restrictions)...)
+	problems = append(problems, consecutiveProblems(restrictions)...)
+	problems = append(problems, classWeightProblems(restrictions)...)
+	problems = append(problems, spaceProblems(restrictions)...)
+	problems = append(problems, keyboardWalkProblems(restrictions)...)
+	problems = append(problems, userInputProblems(restrictions)...)
+	problems = append(problems, oldPasswordProblems(restrictions)...)
+	problems = append(problems, caseProblems(restrictions)...)
+	if restrictions.AllUpperCase && restrictions.MinLowerCase > 0 {
+		problems = append(problems, fmt.Sprintf("minLowerCase (%d) can't be satisfied with allUpperCase", restrictions.MinLowerCase))
+	}
+	if restrictions.AllLowerCase && restrictions.MinUpperCase > 0 {
+		problems = append(problems, fmt.Sprintf("minUpperCase (%d) can't be satisfied with allLowerCase", restrictions.MinUpperCase))
+	}
+	if !restrictions.UserReadable && restrictions.baseCharset() == "" {
+		problems = append(problems, "allowedChars and excludeChars leave no characters to generate the password from")
+	}
+	if restrictions.UserReadable && restrictions.ReadableEngine == "syllable" && (restrictions.charset(syllable.Consonants) == "" || restrictions.charset(syllable.Vowels) == "") {
+		problems = append(problems, "readableEngine=syllable needs at least one consonant and one vowel left by allowedChars and excludeChars")
+	}
+	if restrictions.ClassSpacing > 0 && restrictions.charset(Letters) == "" {
+		problems = append(problems, "classSpacing can't be satisfied because allowedChars and excludeChars leave no letters")
+	}
+	if restrictions.MaxSyllables > 0 && restrictions.MinSyllables > restrictions.MaxSyllables {
+		problems = append(problems, fmt.Sprintf("minSyllables (%d) is larger than maxSyllables (%d)", restrictions.MinSyllables, restrictions.MaxSyllables))
+	}
+	if restrictions.MinLength > restrictions.MaxLength {
+		problems = append(problems, fmt.Sprintf("minLength (%d) is larger than maxLength (%d)", restrictions.MinLength, restrictions.MaxLength))
+	}
+	// Characters of prefix and suffix count towards the group minimums, the
+	// rest has to fit in the room they leave.
+	fixed := restrictions.Prefix + restrictions.Suffix
+	room := restrictions.MaxLength - utf8.RuneCountInString(fixed)
+	required := 0
+	var requiredParameters []string
+	for _, requirement := range requirements {
+		needed := requirement.count - countCharset(fixed, requirement.counts)
+		if needed <= 0 {
+			continue
+		}
+		if requirement.charset == "" {
+			problems = append(problems, fmt.Sprintf("%s (%d) can't be satisfied because no characters of that group are allowed", requirement.parameter, requirement.count))
+		}
+		if requirement.count > restrictions.MaxLength {
+			problems = append(problems, fmt.Sprintf("%s (%d) is larger than maxLength (%d)", requirement.parameter, requirement.count, restrictions.MaxLength))
+		} else if fixed != "" && needed > room {
+			problems = append(problems, fmt.Sprintf("%s (%d) needs %d characters besides the ones of prefix and suffix, which leave %d of maxLength (%d)", requirement.parameter, requirement.count, needed, max(room, 0), restrictions.MaxLength))
+		}
+		required += needed
+		requiredParameters = append(requiredParameters, requirement.parameter)
+	}
+	if len(requiredParameters) > 1 && fixed == "" && required > restrictions.MaxLength {
+		problems = append(problems, fmt.Sprintf("%s require %d characters together, which doesn't fit in maxLength (%d)", strings.Join(requiredParameters, " + "), required, restrictions.MaxLength))
+	} else if len(requiredParameters) > 1 && required > room {
+		problems = append(problems, fmt.Sprintf("%s require %d characters together besides the ones of prefix and suffix, which leave %d of maxLength (%d)", strings.Join(requiredParameters, " + "), required, max(room, 0), restrictions.MaxLength))
+	}
+
+	if restrictions.MaxCharOccurrences > 0 && !restrictions.UserReadable {
+		if capacity := restrictions.repeatCapacity(); restrictions.MinLength > capacity {
+			problems = append(problems, fmt.Sprintf("minLength (%d) needs more characters than the allowed ones used at most maxCharOccurrences (%d) times give (%d)", restrictions.MinLength, restrictions.MaxCharOccurrences, capacity))
+		}
+		for _, requirement := range requirements {
+			if available := len(uniqueChars(restrictions.charset(requirement.counts))) * restrictions.MaxCharOccurrences; requirement.count > available {
+				problems = append(problems, fmt.Sprintf("%s (%d) can't be satisfied using each character at most maxCharOccurrences (%d) times", requirement.parameter, requirement.count, restrictions.MaxCharOccurrences))
+			}
+		}
+	}
+
+	spaced := restrictions.MinDigits + restrictions.MinSpecialChars
+	if restrictions.ClassSpacing > 0 && spaced > 0 {
+		if needed := (spaced-1)*(restrictions.ClassSpacing+1) + 1; needed > restrictions.MaxLength {
+			problems = append(problems, fmt.Sprintf("%d digits and special characters spaced out by classSpacing (%d) need at least %d characters, but maxLength is %d", spaced, restrictions.ClassSpacing, needed, restrictions.MaxLength))
+		}
+	}
+	return feasibilityError(problems)
+}
+
+func feasibilityError(problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New("Restrictions can't be satisfied: " + strings.Join(problems, "; "))
+}
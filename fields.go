@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+func jsonFieldName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func selectFields(v any, fields string) (any, error) {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		return v, nil
+	}
+	available := make(map[string]reflect.Value, value.NumField())
+	for i := 0; i < value.NumField(); i++ {
+		if name := jsonFieldName(value.Type().Field(i)); name != "" {
+			available[name] = value.Field(i)
+		}
+	}
+
+	selected := make(map[string]any)
+	if field, ok := available["error"]; ok {
+		selected["error"] = field.Interface()
+	}
+	for _, name := range strings.Split(fields, ",") {
+		name = strings.TrimSpace(name)
+		field, ok := available[name]
+		if !ok {
+			names := make([]string, 0, len(available))
+			for available := range available {
+				names = append(names, available)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("Parameter fields contains unknown field %q, available fields are %s", name, strings.Join(names, ", "))
+		}
+		selected[name] = field.Interface()
+	}
+	return selected, nil
+}
+
+func responseFields(r *http.Request, status int, v any) (int, any) {
+	fields := r.URL.Query().Get("fields")
+	if fields == "" {
+		return status, v
+	}
+	selected, err := selectFields(v, fields)
+	if err != nil {
+		return 400, Response{Error: err.Error(), Password: ""}
+	}
+	return status, selected
+}
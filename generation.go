@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"password_gen/markov_chain"
+	"time"
+)
+
+type Metadata struct {
+	Strategy     string   `json:"strategy"`
+	Fallback     bool     `json:"fallback,omitempty"`
+	GuessesLog10 *float64 `json:"guessesLog10,omitempty"`
+	EntropyBits  *float64 `json:"entropyBits,omitempty"`
+	Bytes        int      `json:"bytes,omitempty"`
+}
+
+func strategyName(restrictions PasswordRestrictions) string {
+	switch {
+	case restrictions.pattern != nil:
+		return "pattern"
+	case restrictions.Mode == "passphrase", restrictions.Mode == "pin", restrictions.Mode == "memorable", isSecretMode(restrictions.Mode):
+		return restrictions.Mode
+	case restrictions.passphrasePattern != nil:
+		return "passphrasePattern"
+	case restrictions.UserReadable && restrictions.ReadableEngine == "syllable":
+		return "syllable"
+	case restrictions.UserReadable:
+		return "readable"
+	default:
+		return "random"
+	}
+}
+
+func retryGeneratePasswordUntil(maxRetry int, deadline time.Time, restrictions PasswordRestrictions) (string, error) {
+	var password string
+	var err error
+	if restrictions.mustMatch != nil {
+		maxRetry = max(maxRetry, mustMatchAttempts)
+	}
+	if restrictions.RejectDictionaryWords {
+		maxRetry = max(maxRetry, dictionaryAttempts)
+	}
+	rejected := rejections{}
+	for i := 0; ; i++ {
+		password, err = generatePassword(restrictions)
+		if err == nil {
+			recordAttempts(strategyName(restrictions), i+1)
+			return password, nil
+		}
+		recordViolation(err)
+		rejected[violationReason(err)]++
+		if deadline.IsZero() && i+1 >= maxRetry {
+			recordAttempts(strategyName(restrictions), i+1)
+			return password, exhaustedError{err: mustMatchExhausted(err, i+1), diagnosis: rejected.diagnose(i + 1)}
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			recordAttempts(strategyName(restrictions), i+1)
+			return password, exhaustedError{err: errors.New("Password couldn't be generated within the time budget: " + err.Error()), diagnosis: rejected.diagnose(i + 1)}
+		}
+	}
+}
+
+func generateWithinBudget(restrictions PasswordRestrictions) (string, Metadata, error) {
+	if restrictions.UserReadable || restrictions.WordSource == "markov" {
+		return readablePool.generate(restrictions)
+	}
+	return generateNow(restrictions)
+}
+
+func generateNow(restrictions PasswordRestrictions) (string, Metadata, error) {
+	restrictions.candidates = &candidateTracker{}
+	metadata := Metadata{Strategy: strategyName(restrictions)}
+	if isSecretMode(restrictions.Mode) {
+		metadata.Bytes = restrictions.Bytes
+	}
+	var deadline time.Time
+	if config.GenerationTimeout.Duration > 0 {
+		deadline = time.Now().Add(config.GenerationTimeout.Duration)
+	}
+
+	password, err := retryGeneratePasswordUntil(5, deadline, restrictions)
+	if errors.Is(err, markov_chain.ErrModelUnavailable) {
+		return generateWithoutModel(restrictions, deadline)
+	}
+	if err == nil && metadata.Strategy == "readable" {
+		if guesses, err := markov_chain.GuessesLog10(password); err == nil {
+			guesses = math.Round(guesses*100) / 100
+			metadata.GuessesLog10 = &guesses
+		}
+	}
+	if err == nil && restrictions.EntropyBits > 0 {
+		achieved := achievedEntropy(password, restrictions)
+		metadata.EntropyBits = &achieved
+	}
+	if err == nil || !restrictions.Fallback || (metadata.Strategy != "readable" && metadata.Strategy != "syllable") {
+		recordGeneration(metadata.Strategy, metadata, err)
+		if err != nil {
+			return "", metadata, scrubError(err, restrictions.candidates)
+		}
+		return restrictions.format(password), metadata, nil
+	}
+
+	fallback := restrictions
+	fallback.UserReadable = false
+	password, err = retryGeneratePassword(5, fallback)
+	fallbackMetadata := Metadata{Strategy: strategyName(fallback), Fallback: true}
+	recordGeneration(metadata.Strategy, fallbackMetadata, err)
+	if err != nil {
+		return "", fallbackMetadata, scrubError(err, restrictions.candidates)
+	}
+	return restrictions.format(password), fallbackMetadata, nil
+}
+
+// generateWithoutModel generates a readable password with the syllable
+// engine while the markov chain model is unavailable, instead of failing.
+func generateWithoutModel(restrictions PasswordRestrictions, deadline time.Time) (string, Metadata, error) {
+	degraded := restrictions
+	degraded.ReadableEngine = "syllable"
+	if degraded.WordSource == "markov" {
+		degraded.WordSource = "wordlist"
+	}
+	password, err := retryGeneratePasswordUntil(5, deadline, degraded)
+	metadata := Metadata{Strategy: strategyName(degraded), Fallback: true}
+	recordGeneration(strategyName(restrictions), metadata, err)
+	if err != nil {
+		return "", metadata, scrubError(err, restrictions.candidates)
+	}
+	return restrictions.format(password), metadata, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"password_gen/random/randomtest"
+)
+
+func TestRandomPasswordCharactersUniform(t *testing.T) {
+	restrictions, err := parseRestrictions(url.Values{"minLength": {"16"}, "maxLength": {"16"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	charset := []rune(restrictions.baseCharset())
+	counts := map[rune]int{}
+	samples := 0
+	for i := 0; i < 10000; i++ {
+		password, err := generatePassword(restrictions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range password {
+			if !strings.ContainsRune(string(charset), r) {
+				t.Fatalf("Password %q contains %q, which isn't in the charset", password, r)
+			}
+			counts[r]++
+			samples++
+		}
+	}
+	charsetCounts := make([]int, len(charset))
+	for i, r := range charset {
+		charsetCounts[i] = counts[r]
+	}
+	statistic := randomtest.ChiSquare(charsetCounts, samples)
+	if limit := randomtest.ChiSquareLimit(len(charset) - 1); statistic > limit {
+		t.Errorf("Password characters aren't uniform: chi-square %.1f over %d characters, more than %.1f", statistic, samples, limit)
+	}
+}
@@ -3,8 +3,20 @@ module password_gen
 go 1.21.0
 
 require (
+	github.com/fxamacker/cbor/v2 v2.9.0
 	github.com/gorilla/mux v1.8.1
 	github.com/gorilla/schema v1.2.1
+	github.com/gorilla/websocket v1.5.3
 	github.com/mb-14/gomarkov v0.0.0-20231120193207-9cbdc8df67a8
 	github.com/montanaflynn/stats v0.7.1
+	github.com/nbutton23/zxcvbn-go v0.0.0-20210217022336-fa2cb2858354
+	github.com/spf13/cobra v1.10.2
+	github.com/spf13/pflag v1.0.9
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+)
+
+require (
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
 )
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+const defaultGroupSeparator = "-"
+
+func resolveGrouping(restrictions *PasswordRestrictions, query url.Values) error {
+	if restrictions.GroupSize < 0 {
+		return errors.New("Parameter groupSize can't be negative")
+	}
+	if restrictions.GroupSize == 0 {
+		if query.Has("groupSeparator") {
+			restrictions.warn("Parameter groupSeparator ignored because groupSize is not set")
+		}
+		restrictions.GroupSeparator = ""
+		return nil
+	}
+	reason := ""
+	switch {
+	case restrictions.Mode == "passphrase" || restrictions.Mode == "memorable" || restrictions.PassphrasePattern != "":
+		reason = "passphrases are made of words"
+	case restrictions.Pattern != "":
+		reason = "pattern is set"
+	case isSecretMode(restrictions.Mode):
+		reason = "mode=" + restrictions.Mode + " is set"
+	}
+	if reason != "" {
+		restrictions.warn("Parameter groupSize ignored because " + reason)
+		restrictions.GroupSize, restrictions.GroupSeparator = 0, ""
+		return nil
+	}
+	if restrictions.GroupSeparator == "" {
+		restrictions.GroupSeparator = defaultGroupSeparator
+	}
+	if strings.IndexFunc(restrictions.GroupSeparator, func(r rune) bool { return r < ' ' || r > '~' }) >= 0 {
+		return errors.New("Parameter groupSeparator can only contain printable ASCII characters and spaces")
+	}
+	return nil
+}
+
+// grouping returns the size and separator of the groups a password is
+// delivered in, or a size of 0 for a password delivered as is.
+func (restrictions PasswordRestrictions) grouping() (int, string) {
+	switch {
+	case restrictions.GroupSize > 0:
+		return restrictions.GroupSize, restrictions.GroupSeparator
+	case restrictions.spellable():
+		return spellableGroupSize, spellableSeparator
+	}
+	return 0, ""
+}
+
+func groupCharacters(password string, size int, separator string) string {
+	runes := []rune(password)
+	var grouped strings.Builder
+	for i := 0; i < len(runes); i += size {
+		if i > 0 {
+			grouped.WriteString(separator)
+		}
+		grouped.WriteString(string(runes[i:min(i+size, len(runes))]))
+	}
+	return grouped.String()
+}
+
+// format formats a generated password for delivery, after every
+// restriction was checked against its characters.
+func (restrictions PasswordRestrictions) format(password string) string {
+	if size, separator := restrictions.grouping(); size > 0 {
+		return groupCharacters(password, size, separator)
+	}
+	return password
+}
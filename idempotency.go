@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentResponses caps the responses stored at once, so that
+	// clients can't grow memory with unique keys until idempotencyTTL passes.
+	maxIdempotentResponses = 10000
+	// maxIdempotentBodySize caps the size of a stored response body.
+	maxIdempotentBodySize = 1 << 20
+)
+
+var errIdempotencyStoreFull = statusError{status: http.StatusServiceUnavailable, message: "Too many responses to requests with an Idempotency-Key are stored, try again later"}
+
+type idempotentResponse struct {
+	fingerprint string
+	done        bool
+	status      int
+	header      http.Header
+	body        []byte
+	expires     time.Time
+}
+
+type idempotencyStore struct {
+	mu        sync.Mutex
+	responses map[string]*idempotentResponse
+}
+
+var idempotentResponses = &idempotencyStore{responses: map[string]*idempotentResponse{}}
+
+// recordingWriter keeps a copy of the response to replay it. Streamed
+// responses and bodies larger than maxIdempotentBodySize are discarded
+// instead, streams being meant to keep memory flat.
+type recordingWriter struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	discarded bool
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	w.status = status
+	if w.Header().Get("Content-Type") == ndjsonContentType {
+		w.discard()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(200)
+	}
+	if !w.discarded {
+		if w.body.Len()+len(data) > maxIdempotentBodySize {
+			w.discard()
+		} else {
+			w.body.Write(data)
+		}
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) discard() {
+	w.discarded = true
+	w.body = bytes.Buffer{}
+}
+
+func (w *recordingWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func requestFingerprint(r *http.Request) (string, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", bodyError(err, "Could not read body")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n%s\n%s\n", r.Method, r.URL.Path, r.URL.RawQuery, r.Header.Get("Accept"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (s *idempotencyStore) begin(key string, fingerprint string) (*idempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for stored, response := range s.responses {
+		if response.done && now.After(response.expires) {
+			delete(s.responses, stored)
+		}
+	}
+	response, ok := s.responses[key]
+	if !ok && len(s.responses) >= maxIdempotentResponses {
+		return nil, errIdempotencyStoreFull
+	}
+	if ok {
+		if response.fingerprint != fingerprint {
+			return nil, statusError{status: 422, message: "Idempotency-Key was already used for a different request"}
+		}
+		if !response.done {
+			return nil, statusError{status: 409, message: "A request with this Idempotency-Key is still in progress"}
+		}
+		return response, nil
+	}
+	s.responses[key] = &idempotentResponse{fingerprint: fingerprint}
+	return nil, nil
+}
+
+func (s *idempotencyStore) finish(key string, recorder *recordingWriter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if recorder.status >= 500 || recorder.discarded {
+		delete(s.responses, key)
+		return
+	}
+	response := s.responses[key]
+	response.done = true
+	response.status = recorder.status
+	response.header = recorder.Header().Clone()
+	response.body = recorder.body.Bytes()
+	response.expires = time.Now().Add(config.IdempotencyTTL.Duration)
+}
+
+func idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" || config.IdempotencyTTL.Duration <= 0 {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			handleError(w, r, fmt.Errorf("Idempotency-Key can't be longer than %d characters", maxIdempotencyKeyLength))
+			return
+		}
+		fingerprint, err := requestFingerprint(r)
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+
+		key = clientIP(r) + "\x00" + key
+		stored, err := idempotentResponses.begin(key, fingerprint)
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+		if stored != nil {
+			for name, values := range stored.header {
+				w.Header()[name] = values
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.status)
+			w.Write(stored.body)
+			return
+		}
+
+		recorder := &recordingWriter{ResponseWriter: w}
+		defer func() {
+			if recorder.status == 0 {
+				recorder.status = 200
+			}
+			idempotentResponses.finish(key, recorder)
+		}()
+		next(recorder, r)
+	}
+}
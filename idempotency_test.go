@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRecordingWriterDiscardsStreamsAndLargeBodies(t *testing.T) {
+	stream := &recordingWriter{ResponseWriter: httptest.NewRecorder()}
+	stream.Header().Set("Content-Type", ndjsonContentType)
+	stream.Write([]byte("{}\n"))
+	if !stream.discarded || stream.body.Len() != 0 {
+		t.Error("Streamed response is recorded")
+	}
+
+	large := &recordingWriter{ResponseWriter: httptest.NewRecorder()}
+	large.Write([]byte(strings.Repeat("x", maxIdempotentBodySize)))
+	large.Write([]byte("x"))
+	if !large.discarded || large.body.Len() != 0 {
+		t.Errorf("Body of %d bytes is recorded", maxIdempotentBodySize+1)
+	}
+}
+
+func TestIdempotencyStoreIsCapped(t *testing.T) {
+	store := &idempotencyStore{responses: map[string]*idempotentResponse{}}
+	for i := 0; i < maxIdempotentResponses; i++ {
+		if _, err := store.begin(strconv.Itoa(i), "fingerprint"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.begin("new", "fingerprint"); err != errIdempotencyStoreFull {
+		t.Errorf("Full store accepted a new key: %v", err)
+	}
+	if _, err := store.begin("0", "fingerprint"); err == errIdempotencyStoreFull {
+		t.Error("Full store rejected a stored key")
+	}
+}
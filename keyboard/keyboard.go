@@ -0,0 +1,148 @@
+package keyboard
+
+import "sort"
+
+// MinWalkLength is the length of the shortest walk worth reporting, shorter
+// runs of neighbouring keys are common in random strings.
+const MinWalkLength = 4
+
+type key struct {
+	row int
+	x   float64
+}
+
+// Layout maps characters to their keys, shifted characters sharing the key
+// of the unshifted ones.
+type Layout map[rune]key
+
+// rowOffsets are the horizontal positions of the first key of the number
+// row and of the first letter key of the rows below, in key widths.
+var rowOffsets = []float64{0, 1.5, 1.75, 2.25}
+
+// newLayout places the keys of rows, pairs of the unshifted and shifted
+// characters of a row, on a staggered keyboard.
+func newLayout(rows ...[2]string) Layout {
+	layout := Layout{}
+	for row, characters := range rows {
+		for _, keys := range characters {
+			column := 0
+			for _, r := range keys {
+				if _, ok := layout[r]; !ok {
+					layout[r] = key{row: row, x: rowOffsets[row] + float64(column)}
+				}
+				column++
+			}
+		}
+	}
+	return layout
+}
+
+var Layouts = map[string]Layout{
+	"qwerty": newLayout(
+		[2]string{"`1234567890-=", "~!@#$%^&*()_+"},
+		[2]string{"qwertyuiop[]\\", "QWERTYUIOP{}|"},
+		[2]string{"asdfghjkl;'", "ASDFGHJKL:\""},
+		[2]string{"zxcvbnm,./", "ZXCVBNM<>?"},
+	),
+	"qwertz": newLayout(
+		[2]string{"^1234567890ß´", "°!\"§$%&/()=?`"},
+		[2]string{"qwertzuiopü+", "QWERTZUIOPÜ*"},
+		[2]string{"asdfghjklöä#", "ASDFGHJKLÖÄ'"},
+		[2]string{"yxcvbnm,.-", "YXCVBNM;:_"},
+	),
+	"azerty": newLayout(
+		[2]string{"²&é\"'(-è_çà)=", "²1234567890°+"},
+		[2]string{"azertyuiop^$", "AZERTYUIOP¨£"},
+		[2]string{"qsdfghjklmù*", "QSDFGHJKLM%µ"},
+		[2]string{"wxcvbn,;:!", "WXCVBN?./§"},
+	),
+	"dvorak": newLayout(
+		[2]string{"`1234567890[]", "~!@#$%^&*(){}"},
+		[2]string{"',.pyfgcrl/=\\", "\"<>PYFGCRL?+|"},
+		[2]string{"aoeuidhtns-", "AOEUIDHTNS_"},
+		[2]string{";qjkxbmwvz", ":QJKXBMWVZ"},
+	),
+}
+
+// LayoutNames are the names of Layouts, sorted.
+func LayoutNames() []string {
+	names := make([]string, 0, len(Layouts))
+	for name := range Layouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Adjacent reports whether a and b are different keys next to each other,
+// in the same row or in neighbouring rows.
+func (layout Layout) Adjacent(a, b rune) bool {
+	keyA, okA := layout[a]
+	keyB, okB := layout[b]
+	if !okA || !okB || keyA == keyB {
+		return false
+	}
+	dx := keyA.x - keyB.x
+	switch keyA.row - keyB.row {
+	case 0:
+		return dx == 1 || dx == -1
+	case 1, -1:
+		return dx > -1 && dx < 1
+	}
+	return false
+}
+
+type Walk struct {
+	Token  string `json:"token"`
+	Start  int    `json:"start"`
+	Layout string `json:"layout"`
+}
+
+// Walks are the runs of at least minLength characters of password typed by
+// moving to a neighbouring key every time, in any direction, e.g. qwer,
+// 1qaz or zaqwsx, on any of Layouts. Walks within a longer one, e.g. found
+// on another layout, aren't reported.
+func Walks(password string, minLength int) []Walk {
+	runes := []rune(password)
+	type span struct {
+		start, end int
+		layout     string
+	}
+	var spans []span
+	for _, name := range LayoutNames() {
+		layout := Layouts[name]
+		start := 0
+		for i := 1; i <= len(runes); i++ {
+			if i < len(runes) && layout.Adjacent(runes[i-1], runes[i]) {
+				continue
+			}
+			if i-start >= minLength {
+				spans = append(spans, span{start, i, name})
+			}
+			start = i
+		}
+	}
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].end-spans[i].start > spans[j].end-spans[j].start
+	})
+	var kept []span
+	for _, candidate := range spans {
+		contained := false
+		for _, longer := range kept {
+			if longer.start <= candidate.start && candidate.end <= longer.end {
+				contained = true
+			}
+		}
+		if !contained {
+			kept = append(kept, candidate)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		return kept[i].start < kept[j].start
+	})
+	walks := make([]Walk, 0, len(kept))
+	for _, walk := range kept {
+		walks = append(walks, Walk{Token: string(runes[walk.start:walk.end]), Start: walk.start, Layout: walk.layout})
+	}
+	return walks
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"password_gen/random"
+)
+
+const defaultLeetRate = 0.5
+
+// leetSubstitutes are the characters a letter can be written as, the ones
+// people recognize at a glance.
+var leetSubstitutes = map[rune]string{
+	'a': "@4",
+	'e': "3",
+	'i': "1!",
+	'o': "0",
+	's': "$5",
+	't': "7",
+}
+
+func resolveLeet(restrictions *PasswordRestrictions, query url.Values) error {
+	if !restrictions.UserReadable {
+		restrictions.Leet, restrictions.LeetRate = false, 0
+		return nil
+	}
+	if !restrictions.Leet {
+		if query.Has("leetRate") {
+			restrictions.warn("Parameter leetRate ignored because leet is not set")
+			restrictions.LeetRate = 0
+		}
+		return nil
+	}
+	if !query.Has("leetRate") {
+		restrictions.LeetRate = defaultLeetRate
+	}
+	if restrictions.LeetRate <= 0 || restrictions.LeetRate > 1 {
+		return fmt.Errorf("Parameter leetRate has to be larger than 0 and at most 1, not %g", restrictions.LeetRate)
+	}
+	return nil
+}
+
+// leetSpeak substitutes each generated letter having a leet substitute with
+// the leetRate probability, using only substitutes the restrictions permit.
+func leetSpeak(password string, restrictions PasswordRestrictions) (string, error) {
+	substituted := []rune(password)
+	start, end := restrictions.generated(len(substituted))
+	for i := start; i < end; i++ {
+		substitutes := restrictions.charset(leetSubstitutes[toLower(substituted[i])])
+		if substitutes == "" {
+			continue
+		}
+		n, err := random.Intn(1 << 20)
+		if err != nil {
+			return "", err
+		}
+		if float64(n)/(1<<20) >= restrictions.LeetRate {
+			continue
+		}
+		ch, err := randomRune(substitutes)
+		if err != nil {
+			return "", err
+		}
+		substituted[i] = ch
+	}
+	return string(substituted), nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+type statusError struct {
+	status  int
+	message string
+}
+
+func (e statusError) Error() string {
+	return e.message
+}
+
+func errorStatus(err error) int {
+	var withStatus statusError
+	if errors.As(err, &withStatus) {
+		return withStatus.status
+	}
+	return 400
+}
+
+func bodyError(err error, message string) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return statusError{status: http.StatusRequestEntityTooLarge, message: fmt.Sprintf("Body can't be larger than %d bytes", tooLarge.Limit)}
+	}
+	return errors.New(message)
+}
+
+func limitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil && config.MaxBodyBytes > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, config.MaxBodyBytes)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (c Config) checkParameterSizes(query url.Values) error {
+	for key, values := range query {
+		if len(values) > 1 {
+			return fmt.Errorf("Parameter %s can't be given more than once", key)
+		}
+		for _, value := range values {
+			if c.MaxParameterLength > 0 && len(value) > c.MaxParameterLength {
+				return fmt.Errorf("Parameter %s can't be longer than %d characters", key, c.MaxParameterLength)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// localeLetters are the lower case letters of the keyboard layouts of
+// locales, besides the ASCII ones.
+var localeLetters = map[string]string{
+	"de": "äöüß",
+	"es": "áéíñóúü",
+	"fr": "àâæçéèêëîïôœùûüÿ",
+	"pl": "ąćęłńóśźż",
+}
+
+func resolveLocale(restrictions *PasswordRestrictions) error {
+	restrictions.Locale = strings.ToLower(restrictions.Locale)
+	if restrictions.Locale == "" {
+		return nil
+	}
+	if _, ok := localeLetters[restrictions.Locale]; !ok {
+		return fmt.Errorf("Parameter locale has to be de, es, fr or pl, not %q", restrictions.Locale)
+	}
+	reason := ""
+	switch {
+	case restrictions.UserReadable:
+		reason = "userReadable is set"
+	case restrictions.Mode != "":
+		reason = "mode is set"
+	case restrictions.PassphrasePattern != "":
+		reason = "passphrasePattern is set"
+	case restrictions.AllowedChars != "":
+		reason = "allowedChars is set"
+	}
+	if reason != "" {
+		restrictions.warn("Parameter locale ignored because " + reason)
+		restrictions.Locale = ""
+	}
+	return nil
+}
+
+// lower are the lower case letters passwords are generated with.
+func (restrictions PasswordRestrictions) lower() string {
+	return Letters + localeLetters[restrictions.Locale]
+}
+
+// upper are the upper case letters passwords are generated with, locale
+// letters without an upper case form, like ß, left out.
+func (restrictions PasswordRestrictions) upper() string {
+	return UpperLetters + strings.Map(func(r rune) rune {
+		if upper := unicode.ToUpper(r); upper != r {
+			return upper
+		}
+		return -1
+	}, localeLetters[restrictions.Locale])
+}
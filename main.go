@@ -1,127 +1,494 @@
 package main
 
 import (
-	cryptorand "crypto/rand"
-	"encoding/json"
 	"errors"
-	"flag"
 	"fmt"
 	"log"
-	"math/big"
+	"math"
 	"net/http"
 	"net/url"
+	"os"
+	"password_gen/constraint_expression"
 	"password_gen/markov_chain"
+	"password_gen/passphrase"
+	"password_gen/random"
 	"regexp"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/schema"
 )
 
-var decoder = schema.NewDecoder()
+// decoder reads request parameters, ignoring the ones other features (label
+// columns, session fields) read themselves.
+var decoder = func() *schema.Decoder {
+	decoder := schema.NewDecoder()
+	decoder.IgnoreUnknownKeys(true)
+	return decoder
+}()
 
 type Response struct {
-	Error    string `json:"error"`
-	Password string `json:"password"`
+	Error         string                `json:"error"`
+	Password      string                `json:"password"`
+	Passwords     []string              `json:"passwords,omitempty"`
+	Warnings      []string              `json:"warnings,omitempty"`
+	Metadata      *Metadata             `json:"metadata,omitempty"`
+	PolicyApplied *PasswordRestrictions `json:"policyApplied,omitempty"`
+	Composition   *Composition          `json:"composition,omitempty"`
+	Compositions  []Composition         `json:"compositions,omitempty"`
+	Claim         string                `json:"claim,omitempty"`
+	DryRun        *DryRun               `json:"dryRun,omitempty"`
+	Diagnosis     *Diagnosis            `json:"diagnosis,omitempty"`
+	PasswordRules string                `json:"passwordRules,omitempty"`
 }
 
 type PasswordRestrictions struct {
-	MinLength       int  `schema:"minLength"`
-	MaxLength       int  `schema:"maxLength"`
-	MinDigits       int  `schema:"minDigits"`
-	MinSpecialChars int  `schema:"minSpecialChars"`
-	MinLetters      int  `schema:"minLetters"`
-	UserReadable    bool `schema:"userReadable"`
-	AllUpperCase    bool `schemas:"allUpperCase"`
-	AllLowerCase    bool `schemas:"allLowerCase"`
+	MinLength               int     `schema:"minLength" json:"minLength"`
+	MaxLength               int     `schema:"maxLength" json:"maxLength"`
+	MinDigits               int     `schema:"minDigits" json:"minDigits"`
+	MinSpecialChars         int     `schema:"minSpecialChars" json:"minSpecialChars"`
+	MaxDigits               *int    `schema:"maxDigits" json:"maxDigits,omitempty"`
+	MaxSpecialChars         *int    `schema:"maxSpecialChars" json:"maxSpecialChars,omitempty"`
+	MinLetters              int     `schema:"minLetters" json:"minLetters"`
+	MinUpperCase            int     `schema:"minUpperCase" json:"minUpperCase"`
+	MinLowerCase            int     `schema:"minLowerCase" json:"minLowerCase"`
+	UserReadable            bool    `schema:"userReadable" json:"userReadable"`
+	AllUpperCase            bool    `schemas:"allUpperCase" json:"allUpperCase"`
+	AllLowerCase            bool    `schemas:"allLowerCase" json:"allLowerCase"`
+	CaseMode                string  `schema:"caseMode" json:"caseMode,omitempty"`
+	RandomizeCase           float64 `schema:"randomizeCase" json:"randomizeCase,omitempty"`
+	Leet                    bool    `schema:"leet" json:"leet,omitempty"`
+	LeetRate                float64 `schema:"leetRate" json:"leetRate,omitempty"`
+	Expression              string  `schema:"expression" json:"expression,omitempty"`
+	PassphrasePattern       string  `schema:"passphrasePattern" json:"passphrasePattern,omitempty"`
+	Pattern                 string  `schema:"pattern" json:"pattern,omitempty"`
+	EntropyBits             float64 `schema:"entropyBits" json:"entropyBits,omitempty"`
+	PasswordRules           string  `schema:"passwordRules" json:"passwordRules,omitempty"`
+	Preset                  string  `schema:"preset" json:"preset,omitempty"`
+	Wordlist                string  `schema:"wordlist" json:"wordlist,omitempty"`
+	Mode                    string  `schema:"mode" json:"mode,omitempty"`
+	Words                   int     `schema:"words" json:"words,omitempty"`
+	Separator               string  `schema:"separator" json:"separator,omitempty"`
+	Capitalize              bool    `schema:"capitalize" json:"capitalize,omitempty"`
+	PinLength               int     `schema:"pinLength" json:"pinLength,omitempty"`
+	DigitCount              int     `schema:"digitCount" json:"digitCount,omitempty"`
+	SymbolCount             int     `schema:"symbolCount" json:"symbolCount,omitempty"`
+	WordSource              string  `schema:"wordSource" json:"wordSource,omitempty"`
+	Bytes                   int     `schema:"bytes" json:"bytes,omitempty"`
+	NoRepeatedDigits        bool    `schema:"noRepeatedDigits" json:"noRepeatedDigits,omitempty"`
+	NoDigitSequences        bool    `schema:"noDigitSequences" json:"noDigitSequences,omitempty"`
+	NoRepeatChars           bool    `schema:"noRepeatChars" json:"noRepeatChars,omitempty"`
+	MaxCharOccurrences      int     `schema:"maxCharOccurrences" json:"maxCharOccurrences,omitempty"`
+	MaxConsecutiveIdentical int     `schema:"maxConsecutiveIdentical" json:"maxConsecutiveIdentical,omitempty"`
+	NoSequences             bool    `schema:"noSequences" json:"noSequences,omitempty"`
+	MaxSequenceLength       int     `schema:"maxSequenceLength" json:"maxSequenceLength,omitempty"`
+	KeyboardLayout          string  `schema:"keyboardLayout" json:"keyboardLayout,omitempty"`
+	ClassSpacing            int     `schema:"classSpacing" json:"classSpacing"`
+	Fallback                bool    `schema:"fallback" json:"fallback"`
+	MinSyllables            int     `schema:"minSyllables" json:"minSyllables,omitempty"`
+	MaxSyllables            int     `schema:"maxSyllables" json:"maxSyllables,omitempty"`
+	ReadableEngine          string  `schema:"readableEngine" json:"readableEngine,omitempty"`
+	ExcludeChars            string  `schema:"excludeChars" json:"excludeChars,omitempty"`
+	ExcludeAmbiguous        bool    `schema:"excludeAmbiguous" json:"excludeAmbiguous"`
+	Spellable               bool    `schema:"spellable" json:"spellable,omitempty"`
+	GroupSize               int     `schema:"groupSize" json:"groupSize,omitempty"`
+	GroupSeparator          string  `schema:"groupSeparator" json:"groupSeparator,omitempty"`
+	Symbols                 string  `schema:"symbols" json:"symbols,omitempty"`
+	CustomSymbols           string  `schema:"customSymbols" json:"customSymbols,omitempty"`
+	StartsWithLetter        bool    `schema:"startsWithLetter" json:"startsWithLetter,omitempty"`
+	EndsWithAlnum           bool    `schema:"endsWithAlnum" json:"endsWithAlnum,omitempty"`
+	Prefix                  string  `schema:"prefix" json:"prefix,omitempty"`
+	Suffix                  string  `schema:"suffix" json:"suffix,omitempty"`
+	AllowedChars            string  `schema:"allowedChars" json:"allowedChars,omitempty"`
+	UnicodeRanges           string  `schema:"unicodeRanges" json:"unicodeRanges,omitempty"`
+	Locale                  string  `schema:"locale" json:"locale,omitempty"`
+	Balanced                bool    `schema:"balanced" json:"balanced,omitempty"`
+	ClassWeights            string  `schema:"classWeights" json:"classWeights,omitempty"`
+	MustMatch               string  `schema:"mustMatch" json:"mustMatch,omitempty"`
+	PhoneticBlocklist       bool    `schema:"phoneticBlocklist" json:"phoneticBlocklist,omitempty"`
+	RejectDictionaryWords   bool    `schema:"rejectDictionaryWords" json:"rejectDictionaryWords,omitempty"`
+	AllowSpaces             bool    `schema:"allowSpaces" json:"allowSpaces,omitempty"`
+	MinWords                int     `schema:"minWords" json:"minWords,omitempty"`
+	NoKeyboardWalks         bool    `schema:"noKeyboardWalks" json:"noKeyboardWalks,omitempty"`
+	MinWalkLength           int     `schema:"minWalkLength" json:"minWalkLength,omitempty"`
+	UserInputs              string  `schema:"userInputs" json:"userInputs,omitempty"`
+	OldPassword             string  `schema:"oldPassword" json:"-"`
+	OldPasswordHash         string  `schema:"oldPasswordHash" json:"-"`
+	MinPasswordDistance     int     `schema:"minPasswordDistance" json:"minPasswordDistance,omitempty"`
+	MaxCommonSubstring      int     `schema:"maxCommonSubstring" json:"maxCommonSubstring,omitempty"`
+	ADComplexity            bool    `schema:"adComplexity" json:"adComplexity"`
+	Username                string  `schema:"username" json:"username,omitempty"`
+	DisplayName             string  `schema:"displayName" json:"displayName,omitempty"`
+
+	expression        *constraint_expression.Expression
+	mustMatch         *regexp.Regexp
+	passphrasePattern *passphrase.Pattern
+	pattern           []patternSlot
+	words             []string
+	userTokens        []string
+	weights           []int
+	warnings          []string
+	candidates        *candidateTracker
 }
 
 const (
 	Letters      = "abcdefghijklmnopqrstuvwxyz"
+	UpperLetters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
 	Digits       = "0123456789"
 	SpecialChars = "~!@#$%^&*()_+-={}|[]:<>?,./"
 )
 
+var expressionClasses = map[string]string{
+	"letters":  Letters + strings.ToUpper(Letters),
+	"lower":    Letters,
+	"upper":    strings.ToUpper(Letters),
+	"digits":   Digits,
+	"specials": SpecialChars,
+}
+
 func retryGeneratePassword(maxRetry int, restrictions PasswordRestrictions) (string, error) {
+	return retryGeneratePasswordUntil(maxRetry, time.Time{}, restrictions)
+}
+
+func generatePassword(restrictions PasswordRestrictions) (string, error) {
 	var password string
 	var err error
-	for i := 0; i < maxRetry; i++ {
-		password, err = generatePassword(restrictions)
-		if err == nil {
-			return password, nil
+
+	if isSecretMode(restrictions.Mode) {
+		return generateSecret(restrictions)
+	}
+	if restrictions.pattern != nil {
+		password, err = generateFromPattern(restrictions.pattern)
+	} else if restrictions.Mode == "pin" {
+		password, err = generatePin(restrictions)
+	} else if restrictions.Mode == "memorable" {
+		password, err = generateMemorable(restrictions)
+	} else if restrictions.passphrasePattern != nil {
+		if restrictions.words != nil {
+			password, err = restrictions.passphrasePattern.GenerateFrom(restrictions.words, restrictions.charset(Digits), restrictions.charset(restrictions.specials()))
+		} else {
+			password, err = restrictions.passphrasePattern.Generate(restrictions.charset(Digits), restrictions.charset(restrictions.specials()))
+		}
+	} else {
+		password, err = generateCharacterPassword(restrictions)
+	}
+	if err != nil {
+		return "", err
+	}
+	restrictions.candidates.track(password)
+
+	runes := []rune(password)
+	start, end := restrictions.generated(len(runes))
+	prefix, generated, suffix := string(runes[:start]), string(runes[start:end]), string(runes[end:])
+	if restrictions.AllUpperCase {
+		generated = strings.ToUpper(generated)
+	}
+	if restrictions.AllLowerCase {
+		generated = strings.ToLower(generated)
+	}
+	if restrictions.CaseMode == "random" {
+		generated, err = randomizeCase(generated, restrictions.RandomizeCase)
+		if err != nil {
+			return "", err
+		}
+	}
+	password = prefix + generated + suffix
+	if restrictions.CaseMode == "random" {
+		password, err = repairCase(password, restrictions)
+		if err != nil {
+			return "", err
+		}
+		for _, requirement := range groupRequirements(restrictions) {
+			if countCharset(password, requirement.counts) < requirement.count {
+				return "", violation("caseMode", fmt.Errorf("Generated password doesn't satisfy %s after randomizing case, try again", requirement.parameter))
+			}
+		}
+	}
+	repaired, err := repairCharacters(password, restrictions)
+	if err != nil {
+		return "", err
+	}
+	if repaired != password && restrictions.generatesCharacters() {
+		// Replacements can come from another group when none of the same
+		// class is permitted.
+		for _, requirement := range groupRequirements(restrictions) {
+			if countCharset(repaired, requirement.counts) < requirement.count {
+				return "", violation(requirement.parameter, fmt.Errorf("Generated password doesn't satisfy %s after replacing characters which aren't allowed, try again", requirement.parameter))
+			}
+		}
+	}
+	password = repaired
+	restrictions.candidates.track(password)
+	if restrictions.limitsAnchors() && strategyName(restrictions) == "random" {
+		password, err = anchorCharacters(password, restrictions)
+		if err != nil {
+			return "", err
+		}
+		restrictions.candidates.track(password)
+	}
+	if restrictions.limitsRepeats() && strategyName(restrictions) == "random" {
+		password, err = repairRepeats(password, restrictions)
+		if err != nil {
+			return "", err
+		}
+		restrictions.candidates.track(password)
+	}
+	if restrictions.limitsSequences() && strategyName(restrictions) == "random" {
+		password, err = repairSequences(password, restrictions)
+		if err != nil {
+			return "", err
+		}
+		restrictions.candidates.track(password)
+	}
+	if restrictions.limitsConsecutive() && strategyName(restrictions) == "random" {
+		password, err = repairConsecutive(password, restrictions)
+		if err != nil {
+			return "", err
+		}
+		restrictions.candidates.track(password)
+	}
+	if restrictions.limitsGroups() {
+		if err = checkGroupCaps(password, restrictions); err != nil {
+			return "", err
+		}
+	}
+	if restrictions.limitsRepeats() {
+		if err = checkRepeats(password, restrictions); err != nil {
+			return "", err
+		}
+	}
+	if restrictions.limitsSequences() && hasSequences(password, restrictions) {
+		return "", violation("sequences", errors.New("Generated password contains a sequence longer than maxSequenceLength, try again"))
+	}
+	if restrictions.limitsConsecutive() {
+		if err = checkConsecutive(password, restrictions); err != nil {
+			return "", err
+		}
+	}
+	if restrictions.ClassSpacing > 0 && restrictions.generatesCharacters() && !isSpacedOut(password, restrictions.ClassSpacing) {
+		return "", violation("classSpacing", errors.New("Generated password has digits or special characters closer than classSpacing after repairs, try again"))
+	}
+	if restrictions.limitsAnchors() {
+		if err = checkAnchors(password, restrictions); err != nil {
+			return "", err
+		}
+	}
+	if restrictions.AllowSpaces {
+		if err = checkSpaces(password, restrictions); err != nil {
+			return "", err
 		}
 	}
-	return password, err
+	if restrictions.NoKeyboardWalks {
+		if err = checkKeyboardWalks(password, restrictions); err != nil {
+			return "", err
+		}
+	}
+	if strings.ContainsAny(password, restrictions.excluded()) {
+		return "", violation("excludeChars", errors.New("Generated password contains excluded characters, try again"))
+	}
+	if !restrictions.allowed(password) {
+		return "", violation("allowedChars", errors.New("Generated password contains characters outside allowedChars, try again"))
+	}
+	if restrictions.expression != nil && !restrictions.expression.Evaluate(password) {
+		return "", violation("expression", errors.New("Generated password doesn't satisfy expression, try again"))
+	}
+	if restrictions.mustMatch != nil {
+		if err = checkMustMatch(restrictions.format(password), restrictions); err != nil {
+			return "", err
+		}
+	}
+	if restrictions.ADComplexity && len(adComplexityViolations(password, restrictions.Username, restrictions.DisplayName)) > 0 {
+		return "", violation("adComplexity", errors.New("Generated password doesn't satisfy AD complexity, try again"))
+	}
+	if err = checkBlocklist(password); err != nil {
+		return "", err
+	}
+	if err = checkUserInputs(password, restrictions); err != nil {
+		return "", err
+	}
+	if restrictions.PhoneticBlocklist {
+		if err = checkPhoneticBlocklist(password); err != nil {
+			return "", err
+		}
+	}
+	if restrictions.RejectDictionaryWords {
+		if err = checkDictionaryWords(password); err != nil {
+			return "", err
+		}
+	}
+	if err = checkOldPassword(restrictions.format(password), restrictions); err != nil {
+		return "", err
+	}
+	if err = checkBreached(restrictions.format(password)); err != nil {
+		return "", err
+	}
+	if strategyName(restrictions) == "readable" && config.MinReadableGuesses > 0 {
+		guesses, err := markov_chain.GuessesLog10(password)
+		if err != nil {
+			return "", violation("strength", errors.New("User readable password strength can't be estimated, try again later"))
+		}
+		if guesses < math.Log10(config.MinReadableGuesses) {
+			return "", violation("strength", errors.New("Generated password is too easy to guess, try again"))
+		}
+	}
+	return password, nil
 }
 
-func generatePassword(restrictions PasswordRestrictions) (string, error) {
+// generatesCharacters reports whether the password is generated by
+// generateCharacterPassword, rather than by a mode or a pattern.
+func (restrictions PasswordRestrictions) generatesCharacters() bool {
+	return restrictions.Mode == "" && restrictions.pattern == nil && restrictions.passphrasePattern == nil
+}
+
+func generateCharacterPassword(restrictions PasswordRestrictions) (string, error) {
+	password, err := generateCharacters(restrictions.withoutSuffix())
+	if err != nil {
+		return "", err
+	}
+	password += restrictions.Suffix
+	if restrictions.limitsGroups() {
+		password, err = capCharacterGroups(password, restrictions)
+		if err != nil {
+			return "", err
+		}
+		restrictions.candidates.track(password)
+	}
+	if restrictions.ClassSpacing > 0 {
+		password, err = spaceCharacterClasses(password, restrictions)
+		if err != nil {
+			return "", violation("classSpacing", err)
+		}
+	}
+	return password, nil
+}
+
+// generateCharacters generates the password up to the suffix, starting with
+// the prefix.
+func generateCharacters(restrictions PasswordRestrictions) (string, error) {
 	var err error
 	password := ""
-	restrictedChars := ""
+	protected := map[int]bool{}
+	for i := range []rune(restrictions.Prefix) {
+		protected[i] = true
+	}
 
-	password, err = generatePasswordBase(restrictions, password)
+	password, err = generatePasswordBase(restrictions, restrictions.Prefix)
 	if err != nil {
-		return "", err
+		return "", violation("base", err)
 	}
+	restrictions.candidates.track(password)
 	if restrictions.MinLength > 0 {
 		password, err = padPasswordToLength(password, restrictions)
 		if err != nil {
-			return "", err
+			return "", violation("minLength", err)
 		}
+		restrictions.candidates.track(password)
 	}
 	if restrictions.MaxLength > 0 {
 		password = slicePasswordToLength(password, restrictions)
 	}
+	if restrictions.UserReadable && restrictions.Leet {
+		password, err = leetSpeak(password, restrictions)
+		if err != nil {
+			return "", err
+		}
+		restrictions.candidates.track(password)
+	}
 
 	if restrictions.MinSpecialChars > 0 {
-		password, err = fillPasswordWithCharacterGroup(password, restrictions.MinSpecialChars, SpecialChars, restrictions.MaxLength, &restrictedChars)
+		password, err = fillPasswordWithCharacterGroup(password, restrictions.MinSpecialChars, restrictions.countedSpecials(), restrictions.charset(restrictions.specials()), restrictions.MaxLength, protected)
 
 		if err != nil {
-			return "", err
+			return "", violation("minSpecialChars", err)
 		}
+		restrictions.candidates.track(password)
 	}
 	if restrictions.MinDigits > 0 {
-		password, err = fillPasswordWithCharacterGroup(password, restrictions.MinDigits, Digits, restrictions.MaxLength, &restrictedChars)
+		password, err = fillPasswordWithCharacterGroup(password, restrictions.MinDigits, Digits, restrictions.charset(Digits), restrictions.MaxLength, protected)
 
 		if err != nil {
-			return "", err
+			return "", violation("minDigits", err)
 		}
-		restrictedChars += Digits
+		restrictions.candidates.track(password)
 	}
-	if restrictions.MinLetters > 0 {
-		password, err = fillPasswordWithCharacterGroup(password, restrictions.MinLetters, Letters, restrictions.MaxLength, &restrictedChars)
+	if restrictions.MinUpperCase > 0 {
+		password, err = fillPasswordWithCharacterGroup(password, restrictions.MinUpperCase, restrictions.upper(), restrictions.charset(restrictions.upper()), restrictions.MaxLength, protected)
 
 		if err != nil {
-			return "", err
+			return "", violation("minUpperCase", err)
 		}
-		restrictedChars += Letters
+		restrictions.candidates.track(password)
 	}
-	if restrictions.AllUpperCase {
-		password = strings.ToUpper(password)
+	if restrictions.MinLowerCase > 0 {
+		password, err = fillPasswordWithCharacterGroup(password, restrictions.MinLowerCase, restrictions.lower(), restrictions.charset(restrictions.lower()), restrictions.MaxLength, protected)
+
+		if err != nil {
+			return "", violation("minLowerCase", err)
+		}
+		restrictions.candidates.track(password)
 	}
-	if restrictions.AllLowerCase {
-		password = strings.ToLower(password)
+	if restrictions.MinLetters > 0 {
+		password, err = fillPasswordWithCharacterGroup(password, restrictions.MinLetters, restrictions.lower()+restrictions.upper(), restrictions.charset(restrictions.lower()), restrictions.MaxLength, protected)
+
+		if err != nil {
+			return "", violation("minLetters", err)
+		}
+		restrictions.candidates.track(password)
+	}
+	if restrictions.AllowSpaces {
+		password, err = placeSpaces(password, restrictions, protected)
+		if err != nil {
+			return "", violation("spaces", err)
+		}
+		restrictions.candidates.track(password)
 	}
 	return password, nil
 }
 
 func generatePasswordBase(restrictions PasswordRestrictions, prefix string) (string, error) {
 	if restrictions.UserReadable {
-		return generateUserReadablePassword(prefix)
+		return generateUserReadablePassword(prefix, restrictions)
 	} else {
-		return generateRandomPassword(restrictions.MaxLength)
+		length := restrictions.MaxLength
+		if restrictions.MaxCharOccurrences > 0 {
+			length = min(length, restrictions.repeatCapacity())
+		}
+		if restrictions.Balanced {
+			password, err := generateBalancedPassword(length-utf8.RuneCountInString(prefix), restrictions)
+			return prefix + password, err
+		}
+		if restrictions.weights != nil {
+			password, err := generateWeightedPassword(length-utf8.RuneCountInString(prefix), restrictions)
+			return prefix + password, err
+		}
+		password, err := generateRandomPassword(length-utf8.RuneCountInString(prefix), restrictions.baseCharset())
+		return prefix + password, err
 	}
 }
 
-func generateUserReadablePassword(prefix string) (string, error) {
-	return markov_chain.GetProbablePassword(prefix)
+func generateUserReadablePassword(prefix string, restrictions PasswordRestrictions) (string, error) {
+	if restrictions.ReadableEngine == "syllable" {
+		return generateSyllablePassword(prefix, restrictions)
+	}
+	if restrictions.MinSyllables == 0 && restrictions.MaxSyllables == 0 {
+		return markov_chain.GetProbablePassword(prefix)
+	}
+	password, err := markov_chain.GetProbablePasswordMatching(prefix, readableAttempts, func(password string) bool {
+		return restrictions.syllablesFit(strings.TrimPrefix(password, prefix))
+	})
+	if err != nil {
+		return "", violation("syllables", err)
+	}
+	return password, nil
 }
 
-func generateRandomPassword(maxLength int) (string, error) {
-	var password string
+func generateRandomPassword(maxLength int, charset string) (string, error) {
+	password := make([]rune, 0, max(maxLength, 0))
 
 	for i := 0; i < maxLength; i++ {
-		ch, err := randomElement(Letters + Digits + SpecialChars)
+		ch, err := randomRune(charset)
 		if err != nil {
 			return "", err
 		}
@@ -132,34 +499,54 @@ func generateRandomPassword(maxLength int) (string, error) {
 		}
 	}
 
-	return password, nil
+	return string(password), nil
 }
 
-func insertAtRandom(password string, value string) (string, error) {
-	if password == "" {
-		return value, nil
+func insertAtRandom(password []rune, value rune) ([]rune, error) {
+	i, err := random.Intn(len(password) + 1)
+	if err != nil {
+		return nil, err
 	}
+	password = append(password, 0)
+	copy(password[i+1:], password[i:])
+	password[i] = value
+	return password, nil
+}
 
-	n, err := cryptorand.Int(cryptorand.Reader, big.NewInt(int64(len(password))))
+// randomRune picks a random character of s, which can contain multi-byte
+// characters of unicodeRanges.
+func randomRune(s string) (rune, error) {
+	if utf8.RuneCountInString(s) == len(s) {
+		i, err := random.Intn(len(s))
+		if err != nil {
+			return 0, err
+		}
+		return rune(s[i]), nil
+	}
+	runes := []rune(s)
+	i, err := random.Intn(len(runes))
 	if err != nil {
-		return "", err
+		return 0, err
 	}
-	i := n.Int64()
-	return password[0:i] + value + password[i:], nil
+	return runes[i], nil
 }
 
 func randomElement(s string) (string, error) {
-	reader := cryptorand.Reader
-	n, err := cryptorand.Int(reader, big.NewInt(int64(len(s))))
+	r, err := randomRune(s)
 	if err != nil {
 		return "", err
 	}
-	return string(s[n.Int64()]), nil
+	return string(r), nil
 }
 
 func padPasswordToLength(password string, restrictions PasswordRestrictions) (string, error) {
-	if len(password) < restrictions.MinLength {
-		generatedPassword, err := generatePasswordBase(restrictions, password)
+	if utf8.RuneCountInString(password) < restrictions.MinLength {
+		prefix := password
+		if !restrictions.UserReadable {
+			// random characters don't continue what was generated before
+			prefix = ""
+		}
+		generatedPassword, err := generatePasswordBase(restrictions, prefix)
 		if err != nil {
 			return "", err
 		}
@@ -169,123 +556,357 @@ func padPasswordToLength(password string, restrictions PasswordRestrictions) (st
 }
 
 func slicePasswordToLength(password string, restrictions PasswordRestrictions) string {
-	diff := len(password) - restrictions.MaxLength
-	skipFirst, _ := cryptorand.Int(cryptorand.Reader, big.NewInt(int64(2)))
+	runes := []rune(password)
+	diff := len(runes) - restrictions.MaxLength
+	skipFirst, _ := random.Intn(2)
 
 	if diff > 0 {
-		if skipFirst.Int64() > 0 {
-			return password[diff:]
+		if skipFirst > 0 && restrictions.Prefix == "" {
+			return string(runes[diff:])
 		}
-		return password[:len(password)-diff]
+		return string(runes[:len(runes)-diff])
 	}
 	return password
 }
 
-func fillPasswordWithCharacterGroup(password string, characterGroupRestriction int, characterGroup string, maxLength int, restrictedChars *string) (string, error) {
-	charGroupRegexp, err := regexp.Compile(regexp.QuoteMeta(characterGroup))
-	if err != nil {
-		return "", errors.New("Something went wrong")
-	}
-	nonCharGroupRegexp, err := regexp.Compile("[^" + regexp.QuoteMeta(characterGroup+*restrictedChars) + "]")
-	if err != nil {
-		return "", errors.New("Something went wrong")
+// fillPasswordWithCharacterGroup makes sure the password has count characters
+// of counted, replacing unprotected characters of other groups with ones from
+// characterGroup. Positions kept or filled are protected from later groups.
+func fillPasswordWithCharacterGroup(password string, count int, counted string, characterGroup string, maxLength int, protected map[int]bool) (string, error) {
+	runes := []rune(password)
+	present := 0
+	var replaceable []int
+	for i := 0; i < len(runes); i++ {
+		switch {
+		case !strings.ContainsRune(counted, runes[i]):
+			if !protected[i] {
+				replaceable = append(replaceable, i)
+			}
+		case protected[i]:
+			present++
+		case present < count:
+			protected[i] = true
+			present++
+		}
 	}
-	charGroupInPassword := len(charGroupRegexp.FindString(password))
-	missingCharCount := characterGroupRestriction - charGroupInPassword
 
-	for i := 0; i < missingCharCount; i++ {
-		ch, err := randomElement(characterGroup)
+	for ; present < count; present++ {
+		ch, err := randomRune(characterGroup)
 		if err != nil {
 			return "", err
 		}
-		replaceIndexes := nonCharGroupRegexp.FindAllStringIndex(password, -1)
-		if replaceIndexes != nil {
-			randomIndex, err := cryptorand.Int(cryptorand.Reader, big.NewInt(int64(len(replaceIndexes))))
+		if len(replaceable) > 0 {
+			randomIndex, err := random.Intn(len(replaceable))
 			if err != nil {
-				return "", errors.New("Something went wrong while generating password, t1ry again")
+				return "", errors.New("Something went wrong while generating password, try again")
 			}
-			*restrictedChars += ch
-			replaceIndex := replaceIndexes[randomIndex.Int64()]
-			password = password[:replaceIndex[0]] + ch + password[replaceIndex[0]+1:]
+			replaceIndex := replaceable[randomIndex]
+			replaceable = append(replaceable[:randomIndex], replaceable[randomIndex+1:]...)
+			runes[replaceIndex] = ch
+			protected[replaceIndex] = true
+		} else if maxLength > len(runes) {
+			protected[len(runes)] = true
+			runes = append(runes, ch)
 		} else {
-			if maxLength > len(password) {
-				*restrictedChars += ch
-				password += ch
-			} else {
-				return password, errors.New("Something went wrong while generating password, try again")
-			}
+			return string(runes), errors.New("Something went wrong while generating password, try again")
 		}
 	}
-	return password, nil
+	return string(runes), nil
 }
 
 func parseRestrictions(query url.Values) (PasswordRestrictions, error) {
+	return config.parseRestrictions(query)
+}
+
+func (c Config) parseRestrictions(query url.Values) (PasswordRestrictions, error) {
 	var passwordRestrictions PasswordRestrictions
 
-	err := decoder.Decode(&passwordRestrictions, query)
+	if err := c.checkParameterSizes(query); err != nil {
+		return passwordRestrictions, err
+	}
+	query, rulesWarnings, err := mergePasswordRules(query)
 	if err != nil {
 		return passwordRestrictions, err
 	}
+	query, presetWarnings, err := mergePreset(query)
+	if err != nil {
+		return passwordRestrictions, err
+	}
+	err = decoder.Decode(&passwordRestrictions, c.withDefaults(query))
+	if err != nil {
+		return passwordRestrictions, err
+	}
+	passwordRestrictions.warnings = append(append(c.defaultedWarnings(query), rulesWarnings...), presetWarnings...)
 
 	if passwordRestrictions.MaxLength == 0 {
 		passwordRestrictions.MaxLength = 16
+		passwordRestrictions.warn("Parameter maxLength defaulted to 16")
+	}
+	// Modes and patterns set their own length, see warnIgnoredModeParameters.
+	if passwordRestrictions.Mode != "" || passwordRestrictions.PassphrasePattern != "" || passwordRestrictions.Pattern != "" {
+		passwordRestrictions.dropWarning("Parameter maxLength defaulted")
 	}
-	if passwordRestrictions.MinDigits > 0 && passwordRestrictions.MinDigits > passwordRestrictions.MaxLength {
-		return passwordRestrictions, errors.New("Parameter minDigits can't be larger than maxLength")
+	if c.MaxLengthCap > 0 && passwordRestrictions.MaxLength > c.MaxLengthCap {
+		passwordRestrictions.MaxLength = c.MaxLengthCap
+		passwordRestrictions.warn(fmt.Sprintf("Parameter maxLength clamped to the server maximum of %d", c.MaxLengthCap))
 	}
-	if passwordRestrictions.MinSpecialChars > 0 && passwordRestrictions.MinDigits > passwordRestrictions.MaxLength {
-		return passwordRestrictions, errors.New("Parameter minSpecialChars can't be larger than maxLength")
+	if c.MinLengthFloor > 0 {
+		if passwordRestrictions.MaxLength < c.MinLengthFloor {
+			return passwordRestrictions, fmt.Errorf("Parameter maxLength can't be smaller than the server minimum length of %d", c.MinLengthFloor)
+		}
+		if passwordRestrictions.MinLength < c.MinLengthFloor {
+			passwordRestrictions.MinLength = c.MinLengthFloor
+			passwordRestrictions.warn(fmt.Sprintf("Parameter minLength raised to the server minimum of %d", c.MinLengthFloor))
+		}
 	}
-	if passwordRestrictions.MinDigits+passwordRestrictions.MinSpecialChars+passwordRestrictions.MinLetters > passwordRestrictions.MaxLength {
-		return passwordRestrictions, errors.New("Sum of parameters minDigits, minLetters and minSpecialChars can't be larger than maxLength")
+	if !validAllowedChars(passwordRestrictions.AllowedChars) {
+		return passwordRestrictions, errors.New("Parameter allowedChars can only contain printable ASCII characters")
 	}
-	if passwordRestrictions.MaxLength > 0 && passwordRestrictions.MinLength > passwordRestrictions.MaxLength {
-		return passwordRestrictions, errors.New("Parameter minLength can't be larger than maxLength")
+	if passwordRestrictions.ADComplexity && passwordRestrictions.PassphrasePattern == "" && passwordRestrictions.Mode == "" {
+		applyADComplexityPreset(&passwordRestrictions)
+	}
+	switch passwordRestrictions.ReadableEngine {
+	case "", "markov", "syllable":
+	default:
+		return passwordRestrictions, fmt.Errorf("Parameter readableEngine has to be markov or syllable, not %q", passwordRestrictions.ReadableEngine)
+	}
+	if !passwordRestrictions.UserReadable {
+		for _, key := range []string{"minSyllables", "maxSyllables", "readableEngine", "leet", "leetRate"} {
+			if query.Has(key) {
+				passwordRestrictions.warn(fmt.Sprintf("Parameter %s ignored because userReadable is not set", key))
+			}
+		}
+	}
+	if err = resolveCaseMode(&passwordRestrictions, query); err != nil {
+		return passwordRestrictions, err
+	}
+	if err = resolveLeet(&passwordRestrictions, query); err != nil {
+		return passwordRestrictions, err
+	}
+	if err = resolveSymbols(&passwordRestrictions, query); err != nil {
+		return passwordRestrictions, err
+	}
+	if err = resolveRepeats(&passwordRestrictions, query); err != nil {
+		return passwordRestrictions, err
+	}
+	if err = resolveSequences(&passwordRestrictions, query); err != nil {
+		return passwordRestrictions, err
+	}
+	if err = resolveAffixes(&passwordRestrictions); err != nil {
+		return passwordRestrictions, err
+	}
+	if err = resolveGrouping(&passwordRestrictions, query); err != nil {
+		return passwordRestrictions, err
+	}
+	if err = resolveUnicodeRanges(&passwordRestrictions); err != nil {
+		return passwordRestrictions, err
+	}
+	if err = resolveLocale(&passwordRestrictions); err != nil {
+		return passwordRestrictions, err
+	}
+	resolveBalanced(&passwordRestrictions)
+	if err = resolveClassWeights(&passwordRestrictions); err != nil {
+		return passwordRestrictions, err
+	}
+	resolveSpaces(&passwordRestrictions)
+	if err = resolveKeyboardWalks(&passwordRestrictions, query); err != nil {
+		return passwordRestrictions, err
+	}
+	resolveUserInputs(&passwordRestrictions)
+	if err = resolveOldPassword(&passwordRestrictions, query); err != nil {
+		return passwordRestrictions, err
+	}
+	if passwordRestrictions.Expression != "" {
+		passwordRestrictions.expression, err = constraint_expression.Compile(passwordRestrictions.Expression, expressionClasses)
+		if err != nil {
+			return passwordRestrictions, fmt.Errorf("Parameter expression is invalid: %w", err)
+		}
+	}
+	resolvePhoneticBlocklist(&passwordRestrictions)
+	resolveRejectDictionaryWords(&passwordRestrictions)
+	if err = resolveMustMatch(&passwordRestrictions); err != nil {
+		return passwordRestrictions, err
+	}
+	if err = checkMode(passwordRestrictions); err != nil {
+		return passwordRestrictions, err
+	}
+	if passwordRestrictions.Wordlist != "" && (passwordRestrictions.Mode == "passphrase" || passwordRestrictions.Mode == "memorable" || passwordRestrictions.PassphrasePattern != "") {
+		passwordRestrictions.words, err = c.loadWordlist(passwordRestrictions.Wordlist)
+		if err != nil {
+			return passwordRestrictions, errors.New("Parameter wordlist is invalid: " + err.Error())
+		}
+	}
+	if err = c.sizeForEntropy(&passwordRestrictions, query); err != nil {
+		return passwordRestrictions, err
+	}
+	if err = c.enforceEntropyFloor(&passwordRestrictions); err != nil {
+		return passwordRestrictions, err
+	}
+	var pattern *passphrase.Pattern
+	switch {
+	case passwordRestrictions.Mode == "passphrase":
+		pattern, err = passphraseMode(&passwordRestrictions, query)
+	case passwordRestrictions.Mode == "pin":
+		err = pinMode(&passwordRestrictions, query)
+	case passwordRestrictions.Mode == "memorable":
+		pattern, err = memorableMode(&passwordRestrictions, query)
+	case isSecretMode(passwordRestrictions.Mode):
+		err = secretMode(&passwordRestrictions, query)
+	case passwordRestrictions.Pattern != "":
+		passwordRestrictions.pattern, err = parsePattern(passwordRestrictions.Pattern, passwordRestrictions)
+		if err != nil {
+			err = fmt.Errorf("Parameter pattern is invalid: %w", err)
+		}
+	case passwordRestrictions.PassphrasePattern != "":
+		parsed, parseErr := passphrase.ParsePattern(passwordRestrictions.PassphrasePattern)
+		if parseErr != nil {
+			return passwordRestrictions, fmt.Errorf("Parameter passphrasePattern is invalid: %w", parseErr)
+		}
+		pattern = &parsed
+	}
+	if err != nil {
+		return passwordRestrictions, err
+	}
+	warnIgnoredModeParameters(&passwordRestrictions, query)
+	if passwordRestrictions.Mode != "" || passwordRestrictions.PassphrasePattern != "" || passwordRestrictions.Pattern != "" {
+		passwordRestrictions.Prefix, passwordRestrictions.Suffix = "", ""
+	}
+	passwordRestrictions.passphrasePattern = pattern
+	if err = checkFeasibility(passwordRestrictions); err != nil {
+		return passwordRestrictions, err
 	}
 	return passwordRestrictions, nil
 }
 
-func handleError(w http.ResponseWriter, err error) {
-	w.WriteHeader(400)
-	json.NewEncoder(w).Encode(Response{Error: err.Error(), Password: ""})
+func (restrictions *PasswordRestrictions) warn(warning string) {
+	restrictions.warnings = append(restrictions.warnings, warning)
+}
+
+func handleError(w http.ResponseWriter, r *http.Request, err error) {
+	writeResponse(w, r, errorStatus(err), errorResponse(err))
 }
 
 func handlePasswordGen(w http.ResponseWriter, r *http.Request) {
 	password := ""
-	encoder := json.NewEncoder(w)
-	encoder.SetEscapeHTML(false)
-	restrictions, err := parseRestrictions(r.URL.Query())
+	query, err := requestQuery(r)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	restrictions, err := sessionRestrictions(query)
+
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
 
+	composition, err := parseComposition(query)
 	if err != nil {
-		handleError(w, err)
+		handleError(w, r, err)
 		return
 	}
 
-	password, err = retryGeneratePassword(5, restrictions)
+	dryRun, err := parseDryRun(query)
 	if err != nil {
-		handleError(w, err)
+		handleError(w, r, err)
 		return
 	}
-	encoder.Encode(Response{Error: "", Password: password})
+	if dryRun {
+		estimated := estimate(restrictions)
+		writeResponse(w, r, 200, Response{Error: "", Password: "", Warnings: restrictions.warnings, PolicyApplied: &restrictions, DryRun: &estimated})
+		return
+	}
+
+	subject, err := parseClaim(query)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	if query.Has("count") {
+		handlePasswordGenCount(w, r, restrictions, composition)
+		return
+	}
+
+	password, metadata, err := generateWithinBudget(restrictions)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	response := Response{Error: "", Password: password, Warnings: restrictions.warnings, Metadata: &metadata, PolicyApplied: &restrictions}
+	if composition {
+		described := describeComposition(password)
+		response.Composition = &described
+	}
+	if restrictions.PasswordRules != "" {
+		response.PasswordRules = formatPasswordRules(restrictions)
+	}
+	if subject != "" {
+		response.Claim, err = issueClaim(subject, &restrictions, time.Now())
+		if err != nil {
+			handleError(w, r, statusError{status: http.StatusInternalServerError, message: "Claim can't be issued"})
+			return
+		}
+	}
+	writeResponse(w, r, 200, response)
+}
+
+func handlePasswordGenCount(w http.ResponseWriter, r *http.Request, restrictions PasswordRestrictions, composition bool) {
+	count, err := parseBatchCount(r.URL.Query())
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	response := Response{Error: "", Passwords: make([]string, 0, count), Warnings: restrictions.warnings, PolicyApplied: &restrictions}
+	if restrictions.PasswordRules != "" {
+		response.PasswordRules = formatPasswordRules(restrictions)
+	}
+	for i := 0; i < count; i++ {
+		password, metadata, err := generateWithinBudget(restrictions)
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+		if i == 0 {
+			response.Password = password
+			response.Metadata = &metadata
+		}
+		response.Passwords = append(response.Passwords, password)
+		if composition {
+			response.Compositions = append(response.Compositions, describeComposition(password))
+		}
+	}
+	writeResponse(w, r, 200, response)
 }
 
-func handleRequests() {
+func handleRequests(proxies trustedProxies) {
 	myRouter := mux.NewRouter().StrictSlash(true)
+	myRouter.Use(proxies.middleware, limitBody)
+	router := myRouter
+	if config.BasePath != "" {
+		router = myRouter.PathPrefix(config.BasePath).Subrouter()
+	}
 
-	myRouter.HandleFunc("/password-gen", handlePasswordGen).Methods("GET")
-	fmt.Println("Random password generator service listening on port 8080")
+	router.HandleFunc("/password-gen", audited("generation", generationAvailable(withTimeout(randomTimeout, handlePasswordGen)))).Methods("GET")
+	router.HandleFunc("/password-gen/batch", audited("generation", generationAvailable(idempotent(withTimeout(randomTimeout, handlePasswordGenBatch))))).Methods("GET", "POST")
+	router.HandleFunc("/password-gen/stream", audited("generation", generationAvailable(handlePasswordGenStream))).Methods("GET")
+	router.HandleFunc("/password-gen/events", audited("generation", generationAvailable(handlePasswordGenEvents))).Methods("GET")
+	router.HandleFunc("/password-gen/ws", audited("generation", generationAvailable(handlePasswordGenWebSocket))).Methods("GET")
+	router.HandleFunc("/password-gen/credentials", audited("generation", generationAvailable(idempotent(withTimeout(randomTimeout, handleCredentials))))).Methods("POST")
+	router.HandleFunc("/password-gen/csv", audited("generation", generationAvailable(idempotent(withTimeout(randomTimeout, handlePasswordGenCSV))))).Methods("POST")
+	router.HandleFunc("/password-gen/rules", handlePasswordRules).Methods("POST")
+	router.HandleFunc("/password-gen/sessions", handleCreateSession).Methods("POST")
+	router.HandleFunc("/password-gen/sessions/{id}", handleDeleteSession).Methods("DELETE")
+	router.HandleFunc("/password-gen/presets", handlePresets).Methods("GET")
+	router.HandleFunc("/password-gen/score", withTimeout(scoreTimeout, handlePasswordScore)).Methods("POST")
+	myRouter.HandleFunc("/readyz", handleReadiness).Methods("GET")
+	fmt.Printf("Random password generator service listening on port 8080 under %s/\n", config.BasePath)
 	log.Fatal(http.ListenAndServe(":8080", myRouter))
 }
 
 func main() {
-	train := flag.Bool("train", false, "train from dataset")
-	flag.Parse()
-	if *train {
-		err := markov_chain.GeneratePropablePasswordsModel()
-		if err != nil {
-			log.Fatal("Could not train data")
-		}
+	rootCmd.SetArgs(legacyArgs(os.Args[1:]))
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
 	}
-	decoder.IgnoreUnknownKeys(true)
-	handleRequests()
 }
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+)
+
+// maintenance is toggled on the admin listener or with SIGUSR1, to upgrade
+// the stored wordlists and models safely.
+var maintenance atomic.Bool
+
+type MaintenanceStatus struct {
+	Error       string `json:"error"`
+	Maintenance bool   `json:"maintenance"`
+	Message     string `json:"message,omitempty"`
+}
+
+func setMaintenance(enabled bool) {
+	maintenance.Store(enabled)
+	if enabled {
+		fmt.Println("Maintenance mode enabled")
+	} else {
+		fmt.Println("Maintenance mode disabled")
+	}
+}
+
+func toggleMaintenanceOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	go func() {
+		for range signals {
+			setMaintenance(!maintenance.Load())
+		}
+	}()
+}
+
+// generationAvailable answers generation requests with 503 and the
+// maintenanceMessage during maintenance. Without a message generation keeps
+// working.
+func generationAvailable(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if maintenance.Load() && config.MaintenanceMessage != "" {
+			handleError(w, r, statusError{status: http.StatusServiceUnavailable, message: config.MaintenanceMessage})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// managementAvailable rejects changes to the stored wordlists during
+// maintenance.
+func managementAvailable(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if maintenance.Load() {
+			handleError(w, r, statusError{status: http.StatusServiceUnavailable, message: "Wordlist management is disabled during maintenance"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+func maintenanceStatus() MaintenanceStatus {
+	status := MaintenanceStatus{Maintenance: maintenance.Load()}
+	if status.Maintenance {
+		status.Message = config.MaintenanceMessage
+	}
+	return status
+}
+
+func handleGetMaintenance(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, r, 200, maintenanceStatus())
+}
+
+func handleEnableMaintenance(w http.ResponseWriter, r *http.Request) {
+	setMaintenance(true)
+	writeResponse(w, r, 200, maintenanceStatus())
+}
+
+func handleDisableMaintenance(w http.ResponseWriter, r *http.Request) {
+	setMaintenance(false)
+	writeResponse(w, r, 200, maintenanceStatus())
+}
@@ -0,0 +1,162 @@
+package markov_chain
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/mb-14/gomarkov"
+)
+
+type ModelDiff struct {
+	Old, New           ModelInfo
+	SharedVocabulary   []string
+	OnlyOldVocabulary  []string
+	OnlyNewVocabulary  []string
+	SharedStates       int
+	OnlyOldStates      int
+	OnlyNewStates      int
+	MeanDivergence     float64
+	MaxDivergence      float64
+	MaxDivergenceState string
+	OldSamples         []string
+	NewSamples         []string
+}
+
+type distribution map[string]float64
+
+func (data modelData) distributions() map[string]distribution {
+	tokens := make(map[string]string, len(data.Chain.SpoolMap))
+	for token, index := range data.Chain.SpoolMap {
+		tokens[strconv.Itoa(index)] = token
+	}
+	distributions := make(map[string]distribution, len(data.Chain.FreqMat))
+	for current, row := range data.Chain.FreqMat {
+		sum := 0
+		for _, frequency := range row {
+			sum += frequency
+		}
+		if sum == 0 {
+			continue
+		}
+		d := make(distribution, len(row))
+		for next, frequency := range row {
+			d[tokens[next]] = float64(frequency) / float64(sum)
+		}
+		distributions[tokens[current]] = d
+	}
+	return distributions
+}
+
+func (data modelData) vocabulary() map[string]bool {
+	vocabulary := make(map[string]bool)
+	for _, d := range data.distributions() {
+		for token := range d {
+			if token != gomarkov.EndToken {
+				vocabulary[token] = true
+			}
+		}
+	}
+	return vocabulary
+}
+
+func jensenShannon(p, q distribution) float64 {
+	divergence := 0.0
+	for token := range union(p, q) {
+		m := (p[token] + q[token]) / 2
+		if p[token] > 0 {
+			divergence += p[token] * math.Log2(p[token]/m) / 2
+		}
+		if q[token] > 0 {
+			divergence += q[token] * math.Log2(q[token]/m) / 2
+		}
+	}
+	return divergence
+}
+
+func union(p, q distribution) map[string]bool {
+	keys := make(map[string]bool, len(p)+len(q))
+	for key := range p {
+		keys[key] = true
+	}
+	for key := range q {
+		keys[key] = true
+	}
+	return keys
+}
+
+func CompareModels(oldPath, newPath string, samples int) (ModelDiff, error) {
+	var diff ModelDiff
+	oldData, err := readModelData(oldPath)
+	if err != nil {
+		return diff, err
+	}
+	newData, err := readModelData(newPath)
+	if err != nil {
+		return diff, err
+	}
+	diff.Old = describeModelFile(oldPath)
+	diff.New = describeModelFile(newPath)
+
+	oldVocabulary, newVocabulary := oldData.vocabulary(), newData.vocabulary()
+	for token := range oldVocabulary {
+		if newVocabulary[token] {
+			diff.SharedVocabulary = append(diff.SharedVocabulary, token)
+		} else {
+			diff.OnlyOldVocabulary = append(diff.OnlyOldVocabulary, token)
+		}
+	}
+	for token := range newVocabulary {
+		if !oldVocabulary[token] {
+			diff.OnlyNewVocabulary = append(diff.OnlyNewVocabulary, token)
+		}
+	}
+	sort.Strings(diff.SharedVocabulary)
+	sort.Strings(diff.OnlyOldVocabulary)
+	sort.Strings(diff.OnlyNewVocabulary)
+
+	oldDistributions, newDistributions := oldData.distributions(), newData.distributions()
+	total := 0.0
+	for state, oldDistribution := range oldDistributions {
+		newDistribution, ok := newDistributions[state]
+		if !ok {
+			diff.OnlyOldStates++
+			continue
+		}
+		diff.SharedStates++
+		divergence := jensenShannon(oldDistribution, newDistribution)
+		total += divergence
+		if divergence > diff.MaxDivergence {
+			diff.MaxDivergence = divergence
+			diff.MaxDivergenceState = state
+		}
+	}
+	diff.OnlyNewStates = len(newDistributions) - diff.SharedStates
+	if diff.SharedStates > 0 {
+		diff.MeanDivergence = total / float64(diff.SharedStates)
+	}
+
+	if diff.OldSamples, err = sampleModelFile(oldPath, samples); err != nil {
+		return diff, err
+	}
+	if diff.NewSamples, err = sampleModelFile(newPath, samples); err != nil {
+		return diff, err
+	}
+	return diff, nil
+}
+
+func sampleModelFile(path string, samples int) ([]string, error) {
+	model, err := loadModelFile(path)
+	if err != nil {
+		return nil, err
+	}
+	passwords := make([]string, 0, samples)
+	for i := 0; i < samples; i++ {
+		password, err := generateFromModel(model, "")
+		if err != nil {
+			return nil, err
+		}
+		passwords = append(passwords, password)
+	}
+	return passwords, nil
+}
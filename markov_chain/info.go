@@ -0,0 +1,73 @@
+package markov_chain
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type ModelInfo struct {
+	Name       string    `json:"name"`
+	Path       string    `json:"path"`
+	Order      int       `json:"order"`
+	States     int       `json:"states"`
+	SizeBytes  int64     `json:"sizeBytes"`
+	TrainedAt  time.Time `json:"trainedAt"`
+	CaseFolded bool      `json:"caseFolded"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type chainData struct {
+	Order    int                       `json:"int"`
+	SpoolMap map[string]int            `json:"spool_map"`
+	FreqMat  map[string]map[string]int `json:"freq_mat"`
+}
+
+type modelData struct {
+	TrainedAt  time.Time `json:"trained_at"`
+	CaseFolded bool      `json:"case_folded"`
+	Chain      chainData `json:"chain"`
+}
+
+func DescribeModel() ModelInfo {
+	return describeModelFile(modelPath)
+}
+
+func describeModelFile(path string) ModelInfo {
+	info := ModelInfo{
+		Name: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		Path: path,
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	info.SizeBytes = stat.Size()
+
+	data, err := readModelData(path)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	info.Order = data.Chain.Order
+	info.States = len(data.Chain.SpoolMap)
+	info.TrainedAt = data.TrainedAt
+	info.CaseFolded = data.CaseFolded
+	if info.TrainedAt.IsZero() {
+		info.TrainedAt = stat.ModTime().UTC()
+	}
+	return info
+}
+
+func readModelData(path string) (modelData, error) {
+	var data modelData
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return data, err
+	}
+	err = json.Unmarshal(raw, &data)
+	return data, err
+}
@@ -7,19 +7,30 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"password_gen/random"
+	"path/filepath"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/mb-14/gomarkov"
 	"github.com/montanaflynn/stats"
 )
 
 type model struct {
-	Mean   float64         `json:"mean"`
-	StdDev float64         `json:"std_dev"`
-	Chain  *gomarkov.Chain `json:"chain"`
+	Mean           float64         `json:"mean"`
+	StdDev         float64         `json:"std_dev"`
+	TrainedAt      time.Time       `json:"trained_at"`
+	CaseFolded     bool            `json:"case_folded,omitempty"`
+	UpperCaseRatio float64         `json:"upper_case_ratio,omitempty"`
+	Chain          *gomarkov.Chain `json:"chain"`
 }
 
-const minimumProbability = 0.05
+const (
+	minimumProbability = 0.05
+	modelPath          = "./model.json"
+	datasetPath        = "./passwords.txt"
+)
 
 func getDataset(fileName string) []string {
 	file, _ := os.Open(fileName)
@@ -46,25 +57,46 @@ func sequenceProbablity(chain *gomarkov.Chain, input string) float64 {
 	return math.Pow(10, logProb/float64(len(pairs)))
 }
 
-func getScores(chain *gomarkov.Chain) []float64 {
+func getScores(chain *gomarkov.Chain, dataset []string) []float64 {
 	scores := make([]float64, 0)
-	for _, data := range getDataset("./passwords.txt") {
+	for _, data := range dataset {
 		score := sequenceProbablity(chain, data)
 		scores = append(scores, score)
 	}
 	return scores
 }
 
-func saveModel(model model) {
-	jsonObj, _ := json.Marshal(model)
-	err := os.WriteFile("./model.json", jsonObj, 0644)
+// saveModel replaces the model file at once, so that a model retrained while
+// serving is never read half written.
+func saveModel(model model) error {
+	jsonObj, err := json.Marshal(model)
+	if err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(modelPath), ".model-*")
 	if err != nil {
-		fmt.Println(err)
+		return err
+	}
+	defer os.Remove(temp.Name())
+	_, err = temp.Write(jsonObj)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
 	}
+	if err == nil {
+		err = os.Chmod(temp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), modelPath)
+	}
+	return err
 }
 
 func loadModel() (model, error) {
-	data, err := os.ReadFile("./model.json")
+	return loadModelFile(modelPath)
+}
+
+func loadModelFile(path string) (model, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return model{}, err
 	}
@@ -76,38 +108,122 @@ func loadModel() (model, error) {
 	return m, nil
 }
 
+// ErrModelUnavailable is returned while the model is missing or can't be
+// parsed.
+var ErrModelUnavailable = errors.New("User readable password can't be generated, the model is unavailable")
+
 func GetProbablePassword(prefix string) (string, error) {
+	return GetProbablePasswordMatching(prefix, 1, nil)
+}
+
+// GetProbablePasswordMatching samples the chain up to attempts times and
+// returns the first password accepted by accept.
+func GetProbablePasswordMatching(prefix string, attempts int, accept func(string) bool) (string, error) {
 	model, err := loadModel()
 	if err != nil {
-		return "", errors.New("User readable password can't be generated, try again later")
+		return "", ErrModelUnavailable
+	}
+	for i := 0; i < attempts; i++ {
+		password, err := generateFromModel(model, prefix)
+		if err != nil {
+			return "", err
+		}
+		if accept == nil || accept(password) {
+			return password, nil
+		}
 	}
+	return "", errors.New("User readable password matching the restrictions can't be generated, try again")
+}
+
+func generateFromModel(model model, prefix string) (string, error) {
 	order := model.Chain.Order
 	tokens := make([]string, 0)
 	for i := 0; i < order; i++ {
 		tokens = append(tokens, gomarkov.StartToken)
 	}
-	if prefix != "" {
-		tokens = append(tokens, strings.Split(prefix, "")...)
+	chainPrefix := prefix
+	if model.CaseFolded {
+		chainPrefix = strings.ToLower(prefix)
+	}
+	if chainPrefix != "" {
+		tokens = append(tokens, strings.Split(chainPrefix, "")...)
 	}
+	generatedFrom := len(tokens)
 	for tokens[len(tokens)-1] != gomarkov.EndToken {
-		next, err := model.Chain.Generate(tokens[(len(tokens) - order):])
+		next, err := model.Chain.GenerateDeterministic(tokens[(len(tokens)-order):], random.PRNG{})
 		if err != nil {
 			return "", errors.New("User readable password can't be generated, try again later")
 		}
 		tokens = append(tokens, next)
 	}
 
-	return strings.Join(tokens[order:len(tokens)-1], ""), nil
+	if !model.CaseFolded {
+		return strings.Join(tokens[order:len(tokens)-1], ""), nil
+	}
+	generated, err := applyCase(strings.Join(tokens[generatedFrom:len(tokens)-1], ""), model.UpperCaseRatio)
+	if err != nil {
+		return "", err
+	}
+	return prefix + generated, nil
+}
+
+func applyCase(password string, upperCaseRatio float64) (string, error) {
+	if upperCaseRatio <= 0 {
+		return password, nil
+	}
+	runes := []rune(password)
+	for i, r := range runes {
+		if !unicode.IsLower(r) {
+			continue
+		}
+		n, err := random.Intn(1 << 20)
+		if err != nil {
+			return "", err
+		}
+		if float64(n)/(1<<20) < upperCaseRatio {
+			runes[i] = unicode.ToUpper(r)
+		}
+	}
+	return string(runes), nil
 }
 
-func GeneratePropablePasswordsModel() error {
+func upperCaseRatio(dataset []string) float64 {
+	letters, upper := 0, 0
+	for _, data := range dataset {
+		for _, r := range data {
+			if unicode.IsLetter(r) {
+				letters++
+			}
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	if letters == 0 {
+		return 0
+	}
+	return float64(upper) / float64(letters)
+}
+
+func GeneratePropablePasswordsModel(caseFold bool) error {
 	var model model
 	var err error
+	dataset := getDataset(datasetPath)
+	if len(dataset) == 0 {
+		return fmt.Errorf("Dataset %s is empty or can't be read", datasetPath)
+	}
+	if caseFold {
+		model.CaseFolded = true
+		model.UpperCaseRatio = upperCaseRatio(dataset)
+		for i, data := range dataset {
+			dataset[i] = strings.ToLower(data)
+		}
+	}
 	chain := gomarkov.NewChain(2)
-	for _, data := range getDataset("./passwords.txt") {
+	for _, data := range dataset {
 		chain.Add(strings.Split(data, ""))
 	}
-	scores := getScores(chain)
+	scores := getScores(chain, dataset)
 	model.StdDev, err = stats.StandardDeviation(scores)
 	if err != nil {
 		return err
@@ -117,7 +233,22 @@ func GeneratePropablePasswordsModel() error {
 		return err
 	}
 	model.Chain = chain
+	model.TrainedAt = time.Now().UTC()
+
+	return saveModel(model)
+}
 
-	saveModel(model)
-	return nil
+func PasswordProbabilities(passwords []string) ([]float64, error) {
+	model, err := loadModel()
+	if err != nil {
+		return nil, err
+	}
+	probabilities := make([]float64, len(passwords))
+	for i, password := range passwords {
+		if model.CaseFolded {
+			password = strings.ToLower(password)
+		}
+		probabilities[i] = sequenceProbablity(model.Chain, password)
+	}
+	return probabilities, nil
 }
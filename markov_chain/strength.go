@@ -0,0 +1,102 @@
+package markov_chain
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mb-14/gomarkov"
+)
+
+// corpusSize is the number of passwords the chain was trained on, which is
+// the number of transitions out of the start state.
+func (data modelData) corpusSize() int {
+	start := strings.Join(strings.Split(strings.Repeat(gomarkov.StartToken, data.Chain.Order), ""), "_")
+	index, ok := data.Chain.SpoolMap[start]
+	if !ok {
+		return 0
+	}
+	size := 0
+	for _, frequency := range data.Chain.FreqMat[strconv.Itoa(index)] {
+		size += frequency
+	}
+	return size
+}
+
+// strengthModel is the model GuessesLog10 scores with, parsed once and again
+// only when the model file changes, e.g. when it's retrained.
+type strengthModel struct {
+	model
+	unseen float64
+}
+
+var (
+	strengthMutex    sync.Mutex
+	strengthCache    *strengthModel
+	strengthModified time.Time
+	strengthSize     int64
+)
+
+func loadStrengthModel() (*strengthModel, error) {
+	strengthMutex.Lock()
+	defer strengthMutex.Unlock()
+	stat, err := os.Stat(modelPath)
+	if err != nil {
+		return nil, err
+	}
+	if strengthCache != nil && stat.ModTime().Equal(strengthModified) && stat.Size() == strengthSize {
+		return strengthCache, nil
+	}
+	raw, err := os.ReadFile(modelPath)
+	if err != nil {
+		return nil, err
+	}
+	var m model
+	if err = json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	var data modelData
+	if err = json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+	strengthCache = &strengthModel{model: m, unseen: 1 / float64(data.corpusSize()+1)}
+	strengthModified, strengthSize = stat.ModTime(), stat.Size()
+	return strengthCache, nil
+}
+
+// GuessesLog10 estimates the number of guesses an attacker enumerating the
+// chain's outputs by probability needs for password, as log10 of the inverse
+// of its probability. Transitions never seen in training are given the
+// probability 1/(corpus size + 1).
+func GuessesLog10(password string) (float64, error) {
+	m, err := loadStrengthModel()
+	if err != nil {
+		return 0, err
+	}
+	if m.CaseFolded {
+		password = strings.ToLower(password)
+	}
+
+	order := m.Chain.Order
+	tokens := make([]string, 0, len(password)+order+1)
+	for i := 0; i < order; i++ {
+		tokens = append(tokens, gomarkov.StartToken)
+	}
+	tokens = append(tokens, strings.Split(password, "")...)
+	tokens = append(tokens, gomarkov.EndToken)
+
+	logProb := float64(0)
+	for _, pair := range gomarkov.MakePairs(tokens, order) {
+		prob, _ := m.Chain.TransitionProbability(pair.NextState, pair.CurrentState)
+		if prob > 0 {
+			logProb += math.Log10(prob)
+		} else {
+			logProb += math.Log10(m.unseen)
+		}
+	}
+	return -logProb, nil
+}
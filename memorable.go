@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"password_gen/markov_chain"
+	"password_gen/passphrase"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultMemorableWords   = 2
+	defaultMemorableDigits  = 2
+	defaultMemorableSymbols = 1
+	minMarkovWordLength     = 3
+	maxMarkovWordLength     = 8
+	markovWordAttempts      = 100
+)
+
+// memorableMode builds the Word-Word-42! pattern of mode=memorable, the
+// format of the memorable passwords of password managers.
+func memorableMode(restrictions *PasswordRestrictions, query url.Values) (*passphrase.Pattern, error) {
+	if !query.Has("words") {
+		restrictions.Words = defaultMemorableWords
+	}
+	if !query.Has("digitCount") {
+		restrictions.DigitCount = defaultMemorableDigits
+	}
+	if !query.Has("symbolCount") {
+		restrictions.SymbolCount = defaultMemorableSymbols
+	}
+	if !query.Has("separator") {
+		restrictions.Separator = "-"
+	}
+	if !query.Has("capitalize") {
+		restrictions.Capitalize = true
+	}
+	switch restrictions.WordSource {
+	case "":
+		restrictions.WordSource = "wordlist"
+	case "markov":
+		if restrictions.Wordlist != "" {
+			return nil, errors.New("Parameter wordlist can't be combined with wordSource=markov")
+		}
+	case "wordlist":
+	default:
+		return nil, fmt.Errorf("Parameter wordSource has to be wordlist or markov, not %q", restrictions.WordSource)
+	}
+	pattern, err := passphrase.Memorable(restrictions.Words, restrictions.DigitCount, restrictions.SymbolCount, restrictions.Separator, restrictions.Capitalize)
+	if err != nil {
+		return nil, fmt.Errorf("Parameters words, digitCount or symbolCount are invalid: %w", err)
+	}
+	return &pattern, nil
+}
+
+// markovWord samples a word of lowercase letters from the markov chain
+// model, which is less common than the words of a wordlist but still
+// pronounceable.
+func markovWord() (string, error) {
+	word, err := markov_chain.GetProbablePasswordMatching("", markovWordAttempts, func(candidate string) bool {
+		length := utf8.RuneCountInString(candidate)
+		return length >= minMarkovWordLength && length <= maxMarkovWordLength && strings.Trim(strings.ToLower(candidate), Letters) == ""
+	})
+	return strings.ToLower(word), err
+}
+
+func generateMemorable(restrictions PasswordRestrictions) (string, error) {
+	digits, symbols := restrictions.charset(Digits), restrictions.charset(restrictions.specials())
+	switch {
+	case restrictions.WordSource == "markov":
+		return restrictions.passphrasePattern.GenerateWith(markovWord, digits, symbols)
+	case restrictions.words != nil:
+		return restrictions.passphrasePattern.GenerateFrom(restrictions.words, digits, symbols)
+	default:
+		return restrictions.passphrasePattern.Generate(digits, symbols)
+	}
+}
+
+func memorableProblems(restrictions PasswordRestrictions) []string {
+	var problems []string
+	if restrictions.DigitCount > 0 && restrictions.charset(Digits) == "" {
+		problems = append(problems, fmt.Sprintf("digitCount (%d) can't be satisfied because no digits are allowed", restrictions.DigitCount))
+	}
+	if restrictions.SymbolCount > 0 && restrictions.charset(restrictions.specials()) == "" {
+		problems = append(problems, fmt.Sprintf("symbolCount (%d) can't be satisfied because no special characters are allowed", restrictions.SymbolCount))
+	}
+	return problems
+}
@@ -0,0 +1,530 @@
+package metaphone
+
+import (
+	"slices"
+	"strings"
+)
+
+// CodeLength is the length of the codes, as in the original algorithm.
+const CodeLength = 4
+
+type encoder struct {
+	value          []rune
+	slavoGermanic  bool
+	primary        strings.Builder
+	alternate      strings.Builder
+	primaryRunes   int
+	alternateRunes int
+}
+
+func appendCode(code *strings.Builder, length *int, value string) {
+	for _, r := range value {
+		if *length >= CodeLength {
+			return
+		}
+		code.WriteRune(r)
+		*length++
+	}
+}
+
+// add appends primary to the primary code and alternate to the alternate
+// one.
+func (e *encoder) add(primary, alternate string) {
+	appendCode(&e.primary, &e.primaryRunes, primary)
+	appendCode(&e.alternate, &e.alternateRunes, alternate)
+}
+
+func (e *encoder) both(code string) {
+	e.add(code, code)
+}
+
+func (e *encoder) complete() bool {
+	return e.primaryRunes >= CodeLength && e.alternateRunes >= CodeLength
+}
+
+func (e *encoder) at(index int) rune {
+	if index < 0 || index >= len(e.value) {
+		return 0
+	}
+	return e.value[index]
+}
+
+// contains reports whether the length runes at start are one of criteria.
+func (e *encoder) contains(start, length int, criteria ...string) bool {
+	if start < 0 || start+length > len(e.value) {
+		return false
+	}
+	return slices.Contains(criteria, string(e.value[start:start+length]))
+}
+
+func (e *encoder) last() int {
+	return len(e.value) - 1
+}
+
+func isVowel(r rune) bool {
+	return strings.ContainsRune("AEIOUY", r)
+}
+
+// DoubleMetaphone encodes word as Lawrence Philips' double metaphone codes,
+// the primary and the alternate pronunciation, e.g. KMPN for "company".
+// Words sounding alike share a code.
+func DoubleMetaphone(word string) (string, string) {
+	value := strings.ToUpper(strings.TrimSpace(word))
+	e := &encoder{
+		value:         []rune(value),
+		slavoGermanic: strings.ContainsAny(value, "WK") || strings.Contains(value, "CZ") || strings.Contains(value, "WITZ"),
+	}
+	index := 0
+	for _, start := range []string{"GN", "KN", "PN", "WR", "PS"} {
+		if strings.HasPrefix(value, start) {
+			index = 1
+		}
+	}
+	for !e.complete() && index <= e.last() {
+		switch e.at(index) {
+		case 'A', 'E', 'I', 'O', 'U', 'Y':
+			if index == 0 {
+				e.both("A")
+			}
+			index++
+		case 'B':
+			e.both("P")
+			index = e.skip(index, "B")
+		case 'Ç':
+			e.both("S")
+			index++
+		case 'C':
+			index = e.c(index)
+		case 'D':
+			index = e.d(index)
+		case 'F':
+			e.both("F")
+			index = e.skip(index, "F")
+		case 'G':
+			index = e.g(index)
+		case 'H':
+			index = e.h(index)
+		case 'J':
+			index = e.j(index)
+		case 'K':
+			e.both("K")
+			index = e.skip(index, "K")
+		case 'L':
+			index = e.l(index)
+		case 'M':
+			e.both("M")
+			if e.at(index+1) == 'M' || (e.contains(index-1, 3, "UMB") && (index+1 == e.last() || e.contains(index+2, 2, "ER"))) {
+				index += 2
+			} else {
+				index++
+			}
+		case 'N':
+			e.both("N")
+			index = e.skip(index, "N")
+		case 'Ñ':
+			e.both("N")
+			index++
+		case 'P':
+			if e.at(index+1) == 'H' {
+				e.both("F")
+				index += 2
+			} else {
+				e.both("P")
+				index = e.skip(index, "P", "B")
+			}
+		case 'Q':
+			e.both("K")
+			index = e.skip(index, "Q")
+		case 'R':
+			index = e.r(index)
+		case 'S':
+			index = e.s(index)
+		case 'T':
+			index = e.t(index)
+		case 'V':
+			e.both("F")
+			index = e.skip(index, "V")
+		case 'W':
+			index = e.w(index)
+		case 'X':
+			index = e.x(index)
+		case 'Z':
+			index = e.z(index)
+		default:
+			index++
+		}
+	}
+	return e.primary.String(), e.alternate.String()
+}
+
+// skip steps over the rune at index and the next one when it's one of
+// doubled.
+func (e *encoder) skip(index int, doubled ...string) int {
+	if e.contains(index+1, 1, doubled...) {
+		return index + 2
+	}
+	return index + 1
+}
+
+func (e *encoder) germanic() bool {
+	return e.contains(0, 4, "VAN ", "VON ") || e.contains(0, 3, "SCH")
+}
+
+func (e *encoder) c(index int) int {
+	switch {
+	case e.greekCH(index) || e.germanicACH(index):
+		e.both("K")
+		return index + 2
+	case index == 0 && e.contains(index, 6, "CAESAR"):
+		e.both("S")
+		return index + 2
+	case e.contains(index, 2, "CH"):
+		return e.ch(index)
+	case e.contains(index, 2, "CZ") && !e.contains(index-2, 4, "WICZ"):
+		// Czerny
+		e.add("S", "X")
+		return index + 2
+	case e.contains(index+1, 3, "CIA"):
+		// focaccia
+		e.both("X")
+		return index + 3
+	case e.contains(index, 2, "CC") && !(index == 1 && e.at(0) == 'M'):
+		// double c, but not McClelland
+		if e.contains(index+2, 1, "I", "E", "H") && !e.contains(index+2, 2, "HU") {
+			if (index == 1 && e.at(index-1) == 'A') || e.contains(index-1, 5, "UCCEE", "UCCES") {
+				// accident, accede, succeed
+				e.both("KS")
+			} else {
+				// bacci, bertucci
+				e.both("X")
+			}
+			return index + 3
+		}
+		e.both("K")
+		return index + 2
+	case e.contains(index, 2, "CK", "CG", "CQ"):
+		e.both("K")
+		return index + 2
+	case e.contains(index, 2, "CI", "CE", "CY"):
+		if e.contains(index, 3, "CIO", "CIE", "CIA") {
+			e.add("S", "X")
+		} else {
+			e.both("S")
+		}
+		return index + 2
+	}
+	e.both("K")
+	switch {
+	case e.contains(index+1, 2, " C", " Q", " G"):
+		// Mac Caffrey, Mac Gregor
+		return index + 3
+	case e.contains(index+1, 1, "C", "K", "Q") && !e.contains(index+1, 2, "CE", "CI"):
+		return index + 2
+	}
+	return index + 1
+}
+
+// germanicACH is the ch of bacher and macher, but not of ache.
+func (e *encoder) germanicACH(index int) bool {
+	if e.contains(index, 4, "CHIA") {
+		return true
+	}
+	if index <= 1 || isVowel(e.at(index-2)) || !e.contains(index-1, 3, "ACH") {
+		return false
+	}
+	next := e.at(index + 2)
+	return (next != 'I' && next != 'E') || e.contains(index-2, 6, "BACHER", "MACHER")
+}
+
+// greekCH is the initial ch of character, charisma, chorus and chemistry,
+// but not chore.
+func (e *encoder) greekCH(index int) bool {
+	return index == 0 && e.contains(index, 2, "CH") &&
+		(e.contains(index+1, 5, "HARAC", "HARIS") || e.contains(index+1, 3, "HOR", "HYM", "HIA", "HEM")) &&
+		!e.contains(0, 5, "CHORE")
+}
+
+func (e *encoder) ch(index int) int {
+	switch {
+	case index > 0 && e.contains(index, 4, "CHAE"):
+		// Michael
+		e.add("K", "X")
+	case e.germanic() || e.contains(index-2, 6, "ORCHES", "ARCHIT", "ORCHID") || e.contains(index+2, 1, "T", "S") ||
+		((e.contains(index-1, 1, "A", "O", "U", "E") || index == 0) &&
+			(e.contains(index+2, 1, "L", "R", "N", "M", "B", "H", "F", "V", "W", " ") || index+1 == e.last())):
+		// ch sounding kh
+		e.both("K")
+	case index > 0 && e.contains(0, 2, "MC"):
+		e.both("K")
+	case index > 0:
+		e.add("X", "K")
+	default:
+		e.both("X")
+	}
+	return index + 2
+}
+
+func (e *encoder) d(index int) int {
+	switch {
+	case e.contains(index, 2, "DG"):
+		if e.contains(index+2, 1, "I", "E", "Y") {
+			// edge
+			e.both("J")
+			return index + 3
+		}
+		// edgar
+		e.both("TK")
+		return index + 2
+	case e.contains(index, 2, "DT", "DD"):
+		e.both("T")
+		return index + 2
+	}
+	e.both("T")
+	return index + 1
+}
+
+func (e *encoder) g(index int) int {
+	switch {
+	case e.at(index+1) == 'H':
+		return e.gh(index)
+	case e.at(index+1) == 'N':
+		switch {
+		case index == 1 && isVowel(e.at(0)) && !e.slavoGermanic:
+			e.add("KN", "N")
+		case !e.contains(index+2, 2, "EY") && e.at(index+1) != 'Y' && !e.slavoGermanic:
+			e.add("N", "KN")
+		default:
+			e.both("KN")
+		}
+		return index + 2
+	case e.contains(index+1, 2, "LI") && !e.slavoGermanic:
+		e.add("KL", "L")
+		return index + 2
+	case index == 0 && (e.at(index+1) == 'Y' || e.contains(index+1, 2, "ES", "EP", "EB", "EL", "EY", "IB", "IL", "IN", "IE", "EI", "ER")):
+		e.add("K", "J")
+		return index + 2
+	case (e.contains(index+1, 2, "ER") || e.at(index+1) == 'Y') && !e.contains(0, 6, "DANGER", "RANGER", "MANGER") &&
+		!e.contains(index-1, 1, "E", "I") && !e.contains(index-1, 3, "RGY", "OGY"):
+		e.add("K", "J")
+		return index + 2
+	case e.contains(index+1, 1, "E", "I", "Y") || e.contains(index-1, 4, "AGGI", "OGGI"):
+		switch {
+		case e.germanic() || e.contains(index+1, 2, "ET"):
+			e.both("K")
+		case e.contains(index+1, 3, "IER"):
+			e.both("J")
+		default:
+			e.add("J", "K")
+		}
+		return index + 2
+	case e.at(index+1) == 'G':
+		e.both("K")
+		return index + 2
+	}
+	e.both("K")
+	return index + 1
+}
+
+func (e *encoder) gh(index int) int {
+	switch {
+	case index > 0 && !isVowel(e.at(index-1)):
+		e.both("K")
+	case index == 0:
+		if e.at(index+2) == 'I' {
+			e.both("J")
+		} else {
+			e.both("K")
+		}
+	case (index > 1 && e.contains(index-2, 1, "B", "H", "D")) || (index > 2 && e.contains(index-3, 1, "B", "H", "D")) ||
+		(index > 3 && e.contains(index-4, 1, "B", "H")):
+		// hugh
+	case index > 2 && e.at(index-1) == 'U' && e.contains(index-3, 1, "C", "G", "L", "R", "T"):
+		// laugh, cough, rough
+		e.both("F")
+	case e.at(index-1) != 'I':
+		e.both("K")
+	}
+	return index + 2
+}
+
+func (e *encoder) h(index int) int {
+	// only kept when first or between vowels
+	if (index == 0 || isVowel(e.at(index-1))) && isVowel(e.at(index+1)) {
+		e.both("H")
+		return index + 2
+	}
+	return index + 1
+}
+
+func (e *encoder) j(index int) int {
+	if e.contains(index, 4, "JOSE") || e.contains(0, 4, "SAN ") {
+		if (index == 0 && e.at(index+4) == ' ') || len(e.value) == 4 || e.contains(0, 4, "SAN ") {
+			e.both("H")
+		} else {
+			e.add("J", "H")
+		}
+		return index + 1
+	}
+	switch {
+	case index == 0:
+		e.add("J", "A")
+	case isVowel(e.at(index-1)) && !e.slavoGermanic && (e.at(index+1) == 'A' || e.at(index+1) == 'O'):
+		e.add("J", "H")
+	case index == e.last():
+		e.add("J", "")
+	case !e.contains(index+1, 1, "L", "T", "K", "S", "N", "M", "B", "Z") && !e.contains(index-1, 1, "S", "K", "L"):
+		e.both("J")
+	}
+	return e.skip(index, "J")
+}
+
+func (e *encoder) l(index int) int {
+	if e.at(index+1) != 'L' {
+		e.both("L")
+		return index + 1
+	}
+	// Spanish cabrillo, gallegos
+	if (index == len(e.value)-3 && e.contains(index-1, 4, "ILLO", "ILLA", "ALLE")) ||
+		((e.contains(e.last()-1, 2, "AS", "OS") || e.contains(e.last(), 1, "A", "O")) && e.contains(index-1, 4, "ALLE")) {
+		e.add("L", "")
+	} else {
+		e.both("L")
+	}
+	return index + 2
+}
+
+func (e *encoder) r(index int) int {
+	// French rogier
+	if index == e.last() && !e.slavoGermanic && e.contains(index-2, 2, "IE") && !e.contains(index-4, 2, "ME", "MA") {
+		e.add("", "R")
+	} else {
+		e.both("R")
+	}
+	return e.skip(index, "R")
+}
+
+func (e *encoder) s(index int) int {
+	switch {
+	case e.contains(index-1, 3, "ISL", "YSL"):
+		// island, isle, carlisle
+		return index + 1
+	case index == 0 && e.contains(index, 5, "SUGAR"):
+		e.add("X", "S")
+		return index + 1
+	case e.contains(index, 2, "SH"):
+		if e.contains(index+1, 4, "HEIM", "HOEK", "HOLM", "HOLZ") {
+			e.both("S")
+		} else {
+			e.both("X")
+		}
+		return index + 2
+	case e.contains(index, 3, "SIO", "SIA") || e.contains(index, 4, "SIAN"):
+		if e.slavoGermanic {
+			e.both("S")
+		} else {
+			e.add("S", "X")
+		}
+		return index + 3
+	case (index == 0 && e.contains(index+1, 1, "M", "N", "L", "W")) || e.contains(index+1, 1, "Z"):
+		// smith matching schmidt, snider matching schneider
+		e.add("S", "X")
+		return e.skip(index, "Z")
+	case e.contains(index, 2, "SC"):
+		return e.sc(index)
+	}
+	if index == e.last() && e.contains(index-2, 2, "AI", "OI") {
+		// French artois
+		e.add("", "S")
+	} else {
+		e.both("S")
+	}
+	return e.skip(index, "S", "Z")
+}
+
+func (e *encoder) sc(index int) int {
+	switch {
+	case e.at(index+2) == 'H':
+		switch {
+		case e.contains(index+3, 2, "ER", "EN"):
+			// schermerhorn, schenker
+			e.add("X", "SK")
+		case e.contains(index+3, 2, "OO", "UY", "ED", "EM"):
+			// school, schooner
+			e.both("SK")
+		case index == 0 && !isVowel(e.at(3)) && e.at(3) != 'W':
+			e.add("X", "S")
+		default:
+			e.both("X")
+		}
+	case e.contains(index+2, 1, "I", "E", "Y"):
+		e.both("S")
+	default:
+		e.both("SK")
+	}
+	return index + 3
+}
+
+func (e *encoder) t(index int) int {
+	switch {
+	case e.contains(index, 4, "TION"), e.contains(index, 3, "TIA", "TCH"):
+		e.both("X")
+		return index + 3
+	case e.contains(index, 2, "TH") || e.contains(index, 3, "TTH"):
+		// thomas, thames
+		if e.contains(index+2, 2, "OM", "AM") || e.germanic() {
+			e.both("T")
+		} else {
+			e.add("0", "T")
+		}
+		return index + 2
+	}
+	e.both("T")
+	return e.skip(index, "T", "D")
+}
+
+func (e *encoder) w(index int) int {
+	switch {
+	case e.contains(index, 2, "WR"):
+		e.both("R")
+		return index + 2
+	case index == 0 && isVowel(e.at(index+1)):
+		// wasserman matching vasserman
+		e.add("A", "F")
+	case index == 0 && e.contains(index, 2, "WH"):
+		e.both("A")
+	case (index == e.last() && isVowel(e.at(index-1))) || e.contains(index-1, 5, "EWSKI", "EWSKY", "OWSKI", "OWSKY") || e.contains(0, 3, "SCH"):
+		// arnow matching arnoff
+		e.add("", "F")
+	case e.contains(index, 4, "WICZ", "WITZ"):
+		// Polish filipowicz
+		e.add("TS", "FX")
+		return index + 4
+	}
+	return index + 1
+}
+
+func (e *encoder) x(index int) int {
+	if index == 0 {
+		e.both("S")
+		return index + 1
+	}
+	// French breaux
+	if !(index == e.last() && (e.contains(index-3, 3, "IAU", "EAU") || e.contains(index-2, 2, "AU", "OU"))) {
+		e.both("KS")
+	}
+	return e.skip(index, "C", "X")
+}
+
+func (e *encoder) z(index int) int {
+	if e.at(index+1) == 'H' {
+		// Chinese zhao
+		e.both("J")
+		return index + 2
+	}
+	if e.contains(index+1, 2, "ZO", "ZI", "ZA") || (e.slavoGermanic && index > 0 && e.at(index-1) != 'T') {
+		e.add("S", "TS")
+	} else {
+		e.both("S")
+	}
+	return e.skip(index, "Z")
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"expvar"
+	"strconv"
+)
+
+var (
+	strategyRequests     = expvar.NewMap("strategy_requests")
+	strategyFallbacks    = expvar.NewMap("strategy_fallbacks")
+	strategyFailures     = expvar.NewMap("strategy_failures")
+	generationAttempts   = expvar.NewMap("generation_attempts")
+	constraintViolations = expvar.NewMap("constraint_violations")
+	constraintRepairs    = expvar.NewMap("constraint_repairs")
+)
+
+type constraintViolation struct {
+	reason string
+	err    error
+}
+
+func (v constraintViolation) Error() string {
+	return v.err.Error()
+}
+
+func (v constraintViolation) Unwrap() error {
+	return v.err
+}
+
+func violation(reason string, err error) error {
+	return constraintViolation{reason: reason, err: err}
+}
+
+func violationReason(err error) string {
+	var v constraintViolation
+	if errors.As(err, &v) {
+		return v.reason
+	}
+	return "other"
+}
+
+func recordViolation(err error) {
+	constraintViolations.Add(violationReason(err), 1)
+}
+
+func recordRepair(reason string, characters int) {
+	constraintRepairs.Add(reason, int64(characters))
+}
+
+// attemptBuckets are the upper bounds of the attempt counts recorded, so that
+// the keys don't grow with the attempts generationTimeout allows.
+var attemptBuckets = []int{1, 5, 20}
+
+func attemptBucket(attempts int) string {
+	lower := 1
+	for _, upper := range attemptBuckets {
+		if attempts <= upper {
+			if lower == upper {
+				return strconv.Itoa(upper)
+			}
+			return strconv.Itoa(lower) + "-" + strconv.Itoa(upper)
+		}
+		lower = upper + 1
+	}
+	return strconv.Itoa(lower) + "+"
+}
+
+func recordAttempts(strategy string, attempts int) {
+	generationAttempts.Add(strategy+"/"+attemptBucket(attempts), 1)
+}
+
+func recordGeneration(strategy string, metadata Metadata, err error) {
+	strategyRequests.Add(strategy, 1)
+	if metadata.Fallback {
+		strategyFallbacks.Add(strategy, 1)
+	}
+	if err != nil {
+		strategyFailures.Add(strategy, 1)
+	}
+}
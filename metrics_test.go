@@ -0,0 +1,11 @@
+package main
+
+import "testing"
+
+func TestAttemptBucket(t *testing.T) {
+	for attempts, want := range map[int]string{1: "1", 2: "2-5", 5: "2-5", 6: "6-20", 20: "6-20", 21: "21+", 100000: "21+"} {
+		if got := attemptBucket(attempts); got != want {
+			t.Errorf("attemptBucket(%d) = %q, want %q", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+)
+
+var modeParameters = map[string][]string{
+	"passphrase": {"words", "separator", "capitalize"},
+	"pin":        {"pinLength", "noRepeatedDigits", "noDigitSequences"},
+	"memorable":  {"words", "separator", "capitalize", "digitCount", "symbolCount", "wordSource"},
+	"hex":        {"bytes"},
+	"base64":     {"bytes"},
+}
+
+var characterParameters = []string{"minLength", "maxLength", "minDigits", "minSpecialChars", "minLetters", "minUpperCase", "minLowerCase", "maxDigits", "maxSpecialChars", "userReadable", "noRepeatChars", "maxCharOccurrences", "maxConsecutiveIdentical", "noSequences", "startsWithLetter", "endsWithAlnum", "prefix", "suffix"}
+
+func checkMode(restrictions PasswordRestrictions) error {
+	if restrictions.Pattern != "" && (restrictions.Mode != "" || restrictions.PassphrasePattern != "") {
+		return errors.New("Parameter pattern can't be combined with mode or passphrasePattern")
+	}
+	if restrictions.Mode == "" {
+		return nil
+	}
+	if _, ok := modeParameters[restrictions.Mode]; !ok {
+		return fmt.Errorf("Parameter mode has to be passphrase, pin, memorable, hex or base64, not %q", restrictions.Mode)
+	}
+	if restrictions.PassphrasePattern != "" {
+		return errors.New("Parameters mode and passphrasePattern can't be combined")
+	}
+	return nil
+}
+
+// warnIgnoredModeParameters warns about parameters of other modes and, in
+// modes not generating characters one by one, about the character ones.
+func warnIgnoredModeParameters(restrictions *PasswordRestrictions, query url.Values) {
+	reason := "mode=" + restrictions.Mode + " is set"
+	switch {
+	case restrictions.Pattern != "":
+		reason = "pattern is set"
+	case restrictions.PassphrasePattern != "":
+		reason = "passphrasePattern is set"
+	case restrictions.Mode == "":
+		reason = "mode isn't set"
+	}
+	var warned []string
+	for _, mode := range []string{"passphrase", "pin", "memorable", "hex", "base64"} {
+		if mode == restrictions.Mode {
+			continue
+		}
+		for _, key := range modeParameters[mode] {
+			if query.Has(key) && !slices.Contains(modeParameters[restrictions.Mode], key) && !slices.Contains(warned, key) {
+				warned = append(warned, key)
+				restrictions.warn(fmt.Sprintf("Parameter %s ignored because %s", key, reason))
+			}
+		}
+	}
+	if query.Has("wordlist") && restrictions.Mode != "passphrase" && restrictions.Mode != "memorable" && restrictions.PassphrasePattern == "" {
+		restrictions.warn(fmt.Sprintf("Parameter wordlist ignored because %s", reason))
+	}
+	if restrictions.Mode == "" && restrictions.PassphrasePattern == "" && restrictions.Pattern == "" {
+		return
+	}
+	for _, key := range characterParameters {
+		if query.Has(key) {
+			restrictions.warn(fmt.Sprintf("Parameter %s ignored because %s", key, reason))
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log"
+	"net/http"
+	"password_gen/markov_chain"
+	"time"
+)
+
+const (
+	modelAgeCheckInterval = time.Hour
+	modelWebhookTimeout   = 5 * time.Second
+)
+
+var modelRetrains = expvar.NewMap("model_retrains")
+
+func init() {
+	expvar.Publish("model_age_seconds", expvar.Func(func() any {
+		info := markov_chain.DescribeModel()
+		if info.Error != "" {
+			return 0
+		}
+		return int64(time.Since(info.TrainedAt).Seconds())
+	}))
+	expvar.Publish("model_stale", expvar.Func(func() any {
+		if modelStale(markov_chain.DescribeModel()) {
+			return 1
+		}
+		return 0
+	}))
+}
+
+// ModelAgeEvent is posted to modelAgeWebhook when the model gets older than
+// maxModelAge and when it's retrained because of it.
+type ModelAgeEvent struct {
+	Event       string    `json:"event"`
+	Time        time.Time `json:"time"`
+	Model       string    `json:"model"`
+	TrainedAt   time.Time `json:"trainedAt"`
+	Age         Duration  `json:"age"`
+	MaxModelAge Duration  `json:"maxModelAge"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// modelStale reports whether the model was trained longer than maxModelAge
+// ago, readable passwords drifting away from the passwords people use now.
+func modelStale(info markov_chain.ModelInfo) bool {
+	return config.MaxModelAge.Duration > 0 && info.Error == "" && time.Since(info.TrainedAt) > config.MaxModelAge.Duration
+}
+
+func modelAgeProblem(info markov_chain.ModelInfo) string {
+	return fmt.Sprintf("model: trained %s ago, longer than maxModelAge %s", time.Since(info.TrainedAt).Truncate(time.Second), config.MaxModelAge)
+}
+
+// scheduleModelAgeChecks checks the age of the model at start and then
+// hourly, or every maxModelAge when it's shorter, warning about or
+// retraining a stale model depending on modelAgeAction.
+func scheduleModelAgeChecks() {
+	if config.MaxModelAge.Duration <= 0 {
+		return
+	}
+	go func() {
+		var warned time.Time
+		check := func() {
+			info := markov_chain.DescribeModel()
+			if !modelStale(info) {
+				return
+			}
+			if config.ModelAgeAction == "retrain" {
+				retrainModel(info)
+				return
+			}
+			if info.TrainedAt.Equal(warned) {
+				return
+			}
+			warned = info.TrainedAt
+			log.Printf("%s, retrain it with password_gen train", modelAgeProblem(info))
+			notifyModelAge("modelStale", info, nil)
+		}
+		check()
+		ticker := time.NewTicker(min(modelAgeCheckInterval, config.MaxModelAge.Duration))
+		for range ticker.C {
+			check()
+		}
+	}()
+}
+
+// retrainModel trains the model again from the dataset, keeping the case
+// folding it was trained with. Readable passwords use the new model from the
+// next request. The model isn't swapped during maintenance, the next check
+// retrains it once maintenance is over.
+func retrainModel(info markov_chain.ModelInfo) {
+	if maintenance.Load() {
+		log.Printf("%s, retraining after maintenance", modelAgeProblem(info))
+		return
+	}
+	log.Printf("%s, retraining", modelAgeProblem(info))
+	if err := markov_chain.GeneratePropablePasswordsModel(info.CaseFolded); err != nil {
+		modelRetrains.Add("failed", 1)
+		log.Printf("Model %s can't be retrained, retrying in %s: %v", info.Path, min(modelAgeCheckInterval, config.MaxModelAge.Duration), err)
+		notifyModelAge("modelRetrainFailed", info, err)
+		return
+	}
+	modelRetrains.Add("succeeded", 1)
+	notifyModelAge("modelRetrained", markov_chain.DescribeModel(), nil)
+}
+
+func notifyModelAge(event string, info markov_chain.ModelInfo, err error) {
+	if config.ModelAgeWebhook == "" {
+		return
+	}
+	payload := ModelAgeEvent{
+		Event:       event,
+		Time:        time.Now().UTC(),
+		Model:       info.Name,
+		TrainedAt:   info.TrainedAt,
+		Age:         Duration{time.Since(info.TrainedAt).Truncate(time.Second)},
+		MaxModelAge: config.MaxModelAge,
+	}
+	if err != nil {
+		payload.Error = err.Error()
+	}
+	body, _ := json.Marshal(payload)
+	client := &http.Client{Timeout: modelWebhookTimeout}
+	response, err := client.Post(config.ModelAgeWebhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Model age webhook failed: %v", err)
+		return
+	}
+	response.Body.Close()
+	if response.StatusCode >= 300 {
+		log.Printf("Model age webhook failed: %s", response.Status)
+	}
+}
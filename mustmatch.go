@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// mustMatchAttempts is the least number of candidates generated for a
+// mustMatch regex, which random candidates match far less often than they
+// satisfy the other restrictions.
+const mustMatchAttempts = 1000
+
+func resolveMustMatch(restrictions *PasswordRestrictions) error {
+	if restrictions.MustMatch == "" {
+		return nil
+	}
+	expression, err := regexp.Compile(restrictions.MustMatch)
+	if err != nil {
+		return fmt.Errorf("Parameter mustMatch is invalid: %w", err)
+	}
+	restrictions.mustMatch = expression
+	return nil
+}
+
+func checkMustMatch(password string, restrictions PasswordRestrictions) error {
+	if !restrictions.mustMatch.MatchString(password) {
+		return violation("mustMatch", errors.New("Generated password doesn't match mustMatch, try again"))
+	}
+	return nil
+}
+
+// mustMatchExhausted explains that the last of attempts didn't match
+// mustMatch.
+func mustMatchExhausted(err error, attempts int) error {
+	var v constraintViolation
+	if errors.As(err, &v) && v.reason == "mustMatch" {
+		return violation("mustMatch", fmt.Errorf("No generated password matched mustMatch in %d attempts, loosen it or the other restrictions", attempts))
+	}
+	return err
+}
@@ -0,0 +1,31 @@
+package passphrase
+
+import (
+	"fmt"
+	"strings"
+)
+
+const MaxDicewareWords = 20
+
+// Diceware builds the pattern of a diceware passphrase: count words joined
+// by separator, capitalized if asked to.
+func Diceware(count int, separator string, capitalize bool) (Pattern, error) {
+	if count < 1 || count > MaxDicewareWords {
+		return Pattern{}, fmt.Errorf("Passphrase has to have between 1 and %d words", MaxDicewareWords)
+	}
+	kind, name := slotWord, "word"
+	if capitalize {
+		kind, name = slotCapitalizedWord, "Word"
+	}
+	var source []string
+	var pattern Pattern
+	for i := 0; i < count; i++ {
+		if i > 0 && separator != "" {
+			pattern.slots = append(pattern.slots, slot{kind: slotLiteral, literal: separator})
+		}
+		pattern.slots = append(pattern.slots, slot{kind: kind})
+		source = append(source, name)
+	}
+	pattern.source = strings.Join(source, separator)
+	return pattern, nil
+}
@@ -0,0 +1,35 @@
+package passphrase
+
+import (
+	"fmt"
+	"strings"
+)
+
+const MaxMemorableCharacters = 10
+
+// Memorable builds the pattern of a memorable password, like Word-Word-42!:
+// count words joined by separator, followed by digits and symbols after
+// another separator.
+func Memorable(count int, digits int, symbols int, separator string, capitalize bool) (Pattern, error) {
+	pattern, err := Diceware(count, separator, capitalize)
+	if err != nil {
+		return Pattern{}, err
+	}
+	if digits < 0 || digits > MaxMemorableCharacters || symbols < 0 || symbols > MaxMemorableCharacters {
+		return Pattern{}, fmt.Errorf("Memorable password has to have between 0 and %d digits and symbols", MaxMemorableCharacters)
+	}
+	if digits+symbols == 0 {
+		return pattern, nil
+	}
+	if separator != "" {
+		pattern.slots = append(pattern.slots, slot{kind: slotLiteral, literal: separator})
+	}
+	for i := 0; i < digits; i++ {
+		pattern.slots = append(pattern.slots, slot{kind: slotDigit})
+	}
+	for i := 0; i < symbols; i++ {
+		pattern.slots = append(pattern.slots, slot{kind: slotSymbol})
+	}
+	pattern.source += separator + strings.Repeat("D", digits) + strings.Repeat("S", symbols)
+	return pattern, nil
+}
@@ -0,0 +1,215 @@
+package passphrase
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"password_gen/random"
+	"strings"
+	"unicode"
+)
+
+//go:embed eff_large_wordlist.txt
+var effLargeWordlist string
+
+//go:embed profanity.txt
+var profanityWordlist string
+
+var words = ParseWordlist(effLargeWordlist)
+
+var profanity = ParseWordlist(profanityWordlist)
+
+type slotKind int
+
+const (
+	slotLiteral slotKind = iota
+	slotWord
+	slotCapitalizedWord
+	slotUpperWord
+	slotDigit
+	slotSymbol
+)
+
+type slot struct {
+	kind    slotKind
+	literal string
+}
+
+type Pattern struct {
+	source string
+	slots  []slot
+}
+
+var slotNames = []struct {
+	name string
+	kind slotKind
+}{
+	{"symbol", slotSymbol},
+	{"word", slotWord},
+	{"Word", slotCapitalizedWord},
+	{"WORD", slotUpperWord},
+	{"D", slotDigit},
+	{"S", slotSymbol},
+}
+
+// ParseWordlist reads one word per line, taking the last field so that
+// diceware lists with roll numbers parse as well.
+func ParseWordlist(wordlist string) []string {
+	var parsed []string
+	for _, line := range strings.Split(wordlist, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			parsed = append(parsed, fields[len(fields)-1])
+		}
+	}
+	return parsed
+}
+
+func ParsePattern(pattern string) (Pattern, error) {
+	parsed := Pattern{source: pattern}
+	for i := 0; i < len(pattern); {
+		matched := false
+		for _, slotName := range slotNames {
+			if strings.HasPrefix(pattern[i:], slotName.name) {
+				parsed.slots = append(parsed.slots, slot{kind: slotName.kind})
+				i += len(slotName.name)
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		if unicode.IsLetter(rune(pattern[i])) {
+			return parsed, fmt.Errorf("Unknown slot at position %d of pattern %q, expected word, Word, WORD, D, S or symbol", i, pattern)
+		}
+		parsed.slots = append(parsed.slots, slot{kind: slotLiteral, literal: pattern[i : i+1]})
+		i++
+	}
+	if len(parsed.slots) == 0 {
+		return parsed, fmt.Errorf("Pattern %q doesn't contain any slots", pattern)
+	}
+	return parsed, nil
+}
+
+func (p Pattern) String() string {
+	return p.source
+}
+
+func (p Pattern) Generate(digits string, symbols string) (string, error) {
+	return p.GenerateFrom(words, digits, symbols)
+}
+
+// GenerateFrom is Generate drawing words from wordlist instead of the EFF
+// large wordlist.
+func (p Pattern) GenerateFrom(wordlist []string, digits string, symbols string) (string, error) {
+	return p.GenerateWith(func() (string, error) {
+		return randomWord(wordlist)
+	}, digits, symbols)
+}
+
+// GenerateWith is Generate taking the words from nextWord, which returns
+// lowercase ASCII words.
+func (p Pattern) GenerateWith(nextWord func() (string, error), digits string, symbols string) (string, error) {
+	var sb strings.Builder
+	for _, s := range p.slots {
+		switch s.kind {
+		case slotLiteral:
+			sb.WriteString(s.literal)
+		case slotDigit, slotSymbol:
+			charset := digits
+			if s.kind == slotSymbol {
+				charset = symbols
+			}
+			if charset == "" {
+				return "", fmt.Errorf("Pattern %q has a slot without any characters left to choose from", p.source)
+			}
+			i, err := random.Intn(len(charset))
+			if err != nil {
+				return "", err
+			}
+			sb.WriteByte(charset[i])
+		default:
+			word, err := nextWord()
+			if err != nil {
+				return "", err
+			}
+			if s.kind == slotCapitalizedWord {
+				word = strings.ToUpper(word[:1]) + word[1:]
+			} else if s.kind == slotUpperWord {
+				word = strings.ToUpper(word)
+			}
+			sb.WriteString(word)
+		}
+	}
+	return sb.String(), nil
+}
+
+// Estimate returns the entropy in bits of the passphrases p generates from
+// wordlist, or the EFF large wordlist when it's nil, and their shortest and
+// longest length.
+func (p Pattern) Estimate(wordlist []string, digits string, symbols string) (bits float64, shortest int, longest int) {
+	if wordlist == nil {
+		wordlist = words
+	}
+	shortestWord, longestWord := 0, 0
+	for i, word := range wordlist {
+		if i == 0 || len(word) < shortestWord {
+			shortestWord = len(word)
+		}
+		longestWord = max(longestWord, len(word))
+	}
+	for _, s := range p.slots {
+		switch s.kind {
+		case slotLiteral:
+			shortest += len(s.literal)
+			longest += len(s.literal)
+		case slotDigit, slotSymbol:
+			charset := digits
+			if s.kind == slotSymbol {
+				charset = symbols
+			}
+			bits += math.Log2(float64(max(len(charset), 1)))
+			shortest++
+			longest++
+		default:
+			bits += math.Log2(float64(max(len(wordlist), 1)))
+			shortest += shortestWord
+			longest += longestWord
+		}
+	}
+	return bits, shortest, longest
+}
+
+// WordlistSize is the number of words of the EFF large wordlist.
+func WordlistSize() int {
+	return len(words)
+}
+
+func RandomWord() (string, error) {
+	return randomWord(words)
+}
+
+func randomWord(wordlist []string) (string, error) {
+	if len(wordlist) == 0 {
+		return "", errors.New("Wordlist doesn't contain any words")
+	}
+	i, err := random.Intn(len(wordlist))
+	if err != nil {
+		return "", err
+	}
+	return wordlist[i], nil
+}
+
+// IsProfane reports whether word, or its singular, is on the embedded
+// profanity list. Words merely containing one, like "peacock", are fine.
+func IsProfane(word string) bool {
+	word = strings.ToLower(word)
+	for _, profane := range profanity {
+		if word == profane || word == profane+"s" || word == profane+"es" {
+			return true
+		}
+	}
+	return false
+}
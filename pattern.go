@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var patternClasses = map[byte]string{
+	'C': "BCDFGHJKLMNPQRSTVWXYZ",
+	'c': "bcdfghjklmnpqrstvwxyz",
+	'V': "AEIOU",
+	'v': "aeiou",
+	'L': UpperLetters,
+	'l': Letters,
+	'a': Letters + UpperLetters,
+	'9': Digits,
+	'#': SpecialChars,
+}
+
+// patternSlot is either a literal or a charset to draw one character from.
+type patternSlot struct {
+	literal string
+	charset string
+}
+
+// parsePattern expands every class symbol of pattern into a charset,
+// filtered by restrictions. A backslash makes the next character a literal,
+// any other character is copied as is.
+func parsePattern(pattern string, restrictions PasswordRestrictions) ([]patternSlot, error) {
+	var slots []patternSlot
+	for i := 0; i < len(pattern); i++ {
+		ch := pattern[i]
+		if ch == '\\' {
+			if i+1 == len(pattern) {
+				return nil, errors.New("Pattern can't end with an escaping backslash")
+			}
+			i++
+			slots = append(slots, patternSlot{literal: pattern[i : i+1]})
+			continue
+		}
+		class, ok := patternClasses[ch]
+		if !ok {
+			slots = append(slots, patternSlot{literal: pattern[i : i+1]})
+			continue
+		}
+		switch ch {
+		case '#':
+			class = restrictions.specials()
+		case 'L':
+			class = restrictions.upper()
+		case 'l':
+			class = restrictions.lower()
+		case 'a':
+			class = restrictions.lower() + restrictions.upper()
+		}
+		charset := restrictions.charset(class)
+		if charset == "" {
+			return nil, fmt.Errorf("Pattern class %c at position %d has no characters left after allowedChars and excludeChars", ch, i)
+		}
+		slots = append(slots, patternSlot{charset: charset})
+	}
+	return slots, nil
+}
+
+func generateFromPattern(slots []patternSlot) (string, error) {
+	var sb strings.Builder
+	for _, slot := range slots {
+		if slot.charset == "" {
+			sb.WriteString(slot.literal)
+			continue
+		}
+		ch, err := randomElement(slot.charset)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(ch)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"password_gen/dictionary"
+	"password_gen/metaphone"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minPhoneticCodeLength is the least length of the code of a blocked term
+// matched phonetically, shorter codes sound like too many candidates.
+const minPhoneticCodeLength = 3
+
+func resolvePhoneticBlocklist(restrictions *PasswordRestrictions) {
+	if restrictions.PhoneticBlocklist && !restrictions.UserReadable {
+		restrictions.warn("Parameter phoneticBlocklist ignored because userReadable is not set")
+		restrictions.PhoneticBlocklist = false
+	}
+}
+
+// soundalikeTerm is the blocked term a part of password sounds like, its
+// double metaphone codes sharing one with the term's. Leet substitutes are
+// read as their letters and other non-letters are skipped, so c0mp4ny and
+// kompany both sound like company.
+func soundalikeTerm(password string) (string, bool) {
+	terms := blockedTermsList()
+	if len(terms) == 0 {
+		return "", false
+	}
+	letters := []rune(strings.Map(func(r rune) rune {
+		if !unicode.IsLetter(r) {
+			return -1
+		}
+		return r
+	}, dictionary.Unleet(password)))
+
+	// codes are the codes of the parts of password of every length.
+	codes := map[int]map[string]bool{}
+	sounds := func(length int, code string) bool {
+		if length < 1 || length > len(letters) {
+			return false
+		}
+		if codes[length] == nil {
+			codes[length] = map[string]bool{}
+			for i := 0; i+length <= len(letters); i++ {
+				primary, alternate := metaphone.DoubleMetaphone(string(letters[i : i+length]))
+				codes[length][primary] = true
+				codes[length][alternate] = true
+			}
+		}
+		return codes[length][code]
+	}
+	for _, term := range terms {
+		term = strings.Map(func(r rune) rune {
+			if !unicode.IsLetter(r) {
+				return -1
+			}
+			return r
+		}, term)
+		primary, alternate := metaphone.DoubleMetaphone(term)
+		if utf8.RuneCountInString(primary) < minPhoneticCodeLength {
+			continue
+		}
+		// Spellings of a term differ by a letter or two, e.g. acme and
+		// akmee.
+		length := utf8.RuneCountInString(term)
+		for n := length - 1; n <= length+2; n++ {
+			if sounds(n, primary) || (alternate != primary && utf8.RuneCountInString(alternate) >= minPhoneticCodeLength && sounds(n, alternate)) {
+				return term, true
+			}
+		}
+	}
+	return "", false
+}
+
+func checkPhoneticBlocklist(password string) error {
+	if _, found := soundalikeTerm(password); found {
+		return violation("phoneticBlocklist", errors.New("Generated password sounds like a blocked term, try again"))
+	}
+	return nil
+}
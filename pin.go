@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	defaultPinLength = 6
+	minPinLength     = 4
+	maxPinLength     = 64
+)
+
+func pinMode(restrictions *PasswordRestrictions, query url.Values) error {
+	if restrictions.PinLength == 0 {
+		restrictions.PinLength = defaultPinLength
+		restrictions.warn(fmt.Sprintf("Parameter pinLength defaulted to %d", defaultPinLength))
+	}
+	if restrictions.PinLength < minPinLength || restrictions.PinLength > maxPinLength {
+		return fmt.Errorf("Parameter pinLength has to be between %d and %d", minPinLength, maxPinLength)
+	}
+	return nil
+}
+
+// pinDigits are the digits which can follow pin without breaking the
+// repeated digit and sequence rules.
+func pinDigits(pin string, digits string, restrictions PasswordRestrictions) string {
+	n := len(pin)
+	return strings.Map(func(r rune) rune {
+		next := byte(r)
+		if restrictions.NoRepeatedDigits && n > 0 && pin[n-1] == next {
+			return -1
+		}
+		if restrictions.NoDigitSequences && n > 1 {
+			step := int(pin[n-1]) - int(pin[n-2])
+			if (step == 1 || step == -1) && int(next)-int(pin[n-1]) == step {
+				return -1
+			}
+		}
+		return r
+	}, digits)
+}
+
+// generatePin draws each digit from the ones the rules still allow, so a
+// pin never has to be generated again.
+func generatePin(restrictions PasswordRestrictions) (string, error) {
+	digits := restrictions.charset(Digits)
+	pin := ""
+	for len(pin) < restrictions.PinLength {
+		allowed := pinDigits(pin, digits, restrictions)
+		if allowed == "" {
+			return "", violation("pin", errors.New("Pin can't be continued with the allowed digits"))
+		}
+		digit, err := randomElement(allowed)
+		if err != nil {
+			return "", err
+		}
+		pin += digit
+	}
+	return pin, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"password_gen/dictionary"
+	"strings"
+	"unicode/utf8"
+)
+
+func countCharset(password string, charset string) int {
+	count := 0
+	for _, r := range password {
+		if strings.ContainsRune(charset, r) {
+			count++
+		}
+	}
+	return count
+}
+
+func policyViolations(password string, restrictions PasswordRestrictions) []string {
+	var violations []string
+	length := utf8.RuneCountInString(password)
+	if restrictions.MinLength > 0 && length < restrictions.MinLength {
+		violations = append(violations, fmt.Sprintf("shorter than minLength (%d)", restrictions.MinLength))
+	}
+	if restrictions.MaxLength > 0 && length > restrictions.MaxLength {
+		violations = append(violations, fmt.Sprintf("longer than maxLength (%d)", restrictions.MaxLength))
+	}
+	for _, requirement := range groupRequirements(restrictions) {
+		if count := countCharset(password, requirement.counts); count < requirement.count {
+			violations = append(violations, fmt.Sprintf("%d of %s (%d)", count, requirement.parameter, requirement.count))
+		}
+	}
+	if restrictions.limitsGroups() {
+		runes := []rune(password)
+		for _, group := range groupCaps(restrictions) {
+			if count := len(classPositions(runes, group.class, 0, len(runes))); group.max != nil && count > *group.max {
+				violations = append(violations, fmt.Sprintf("%d of %s (%d)", count, group.parameter, *group.max))
+			}
+		}
+	}
+	if !restrictions.allowed(password) {
+		violations = append(violations, "characters outside allowedChars")
+	}
+	if restrictions.AllUpperCase && password != strings.ToUpper(password) {
+		violations = append(violations, "not allUpperCase")
+	}
+	if restrictions.AllLowerCase && password != strings.ToLower(password) {
+		violations = append(violations, "not allLowerCase")
+	}
+	if restrictions.ClassSpacing > 0 && !isSpacedOut(password, restrictions.ClassSpacing) {
+		violations = append(violations, fmt.Sprintf("digits and special characters closer than classSpacing (%d)", restrictions.ClassSpacing))
+	}
+	if restrictions.expression != nil && !restrictions.expression.Evaluate(password) {
+		violations = append(violations, "doesn't satisfy expression")
+	}
+	if restrictions.mustMatch != nil && !restrictions.mustMatch.MatchString(password) {
+		violations = append(violations, "doesn't match mustMatch")
+	}
+	if restrictions.AllowSpaces {
+		violations = append(violations, spaceViolations(password, restrictions)...)
+	}
+	if restrictions.NoKeyboardWalks {
+		violations = append(violations, keyboardWalkViolations(password, restrictions)...)
+	}
+	violations = append(violations, userInputViolations(password, restrictions)...)
+	violations = append(violations, oldPasswordViolations(password, restrictions)...)
+	if restrictions.RejectDictionaryWords {
+		if word, found := dictionary.CommonWord(password, minDictionaryWordLength); found {
+			violations = append(violations, fmt.Sprintf("contains the dictionary word '%s'", word))
+		}
+	}
+	if restrictions.ADComplexity {
+		violations = append(violations, adComplexityViolations(password, restrictions.Username, restrictions.DisplayName)...)
+	}
+	return violations
+}
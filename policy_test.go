@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestUnicodeRangesPasswordsSatisfyPolicy(t *testing.T) {
+	for _, query := range []url.Values{
+		{"minSpecialChars": {"1"}, "maxSpecialChars": {"1"}, "unicodeRanges": {"latin1"}},
+		{"minSpecialChars": {"3"}, "maxSpecialChars": {"4"}, "minDigits": {"2"}, "unicodeRanges": {"latin1,greek"}},
+		{"minSpecialChars": {"2"}, "unicodeRanges": {"cyrillic"}},
+	} {
+		restrictions, err := parseRestrictions(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 200; i++ {
+			password, err := retryGeneratePassword(100, restrictions)
+			if err != nil {
+				t.Fatalf("%s: %v", query.Encode(), err)
+			}
+			if violations := policyViolations(password, restrictions); len(violations) > 0 {
+				t.Fatalf("%s: %q violates the policy: %v", query.Encode(), password, violations)
+			}
+		}
+	}
+}
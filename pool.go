@@ -0,0 +1,93 @@
+package main
+
+import (
+	"expvar"
+	"log"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+const defaultReadableQueue = 256
+
+var (
+	readablePoolQueued   = expvar.NewInt("readable_pool_queued")
+	readablePoolRejected = expvar.NewInt("readable_pool_rejected")
+)
+
+type generationResult struct {
+	password string
+	metadata Metadata
+	err      error
+}
+
+type generationJob struct {
+	restrictions PasswordRestrictions
+	result       chan generationResult
+}
+
+// generationPool runs generation on a fixed number of workers, so that
+// expensive strategies can't take every CPU from the cheap ones. Requests
+// which don't fit in the queue while all workers are busy are rejected.
+type generationPool struct {
+	start sync.Once
+	jobs  chan generationJob
+}
+
+// readablePool generates readable passwords, which sample the markov chain
+// model, separately from random ones, generated right away.
+var readablePool = &generationPool{}
+
+var errGenerationPanicked = statusError{status: http.StatusInternalServerError, message: "Something went wrong while generating password, try again"}
+
+var errReadablePoolFull = statusError{status: http.StatusServiceUnavailable, message: "Too many readable passwords are being generated, try again later"}
+
+func readableWorkers() int {
+	if config.ReadableWorkers > 0 {
+		return config.ReadableWorkers
+	}
+	return max(1, runtime.NumCPU()/2)
+}
+
+// runJob generates the password of job. A panic, e.g. of random.PRNG when the
+// system random source fails, fails the job instead of the whole server.
+func runJob(job generationJob) (result generationResult) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Printf("Readable password generation panicked: %v\n%s", recovered, debug.Stack())
+			result = generationResult{metadata: Metadata{Strategy: strategyName(job.restrictions)}, err: errGenerationPanicked}
+		}
+	}()
+	password, metadata, err := generateNow(job.restrictions)
+	return generationResult{password: password, metadata: metadata, err: err}
+}
+
+func (p *generationPool) generate(restrictions PasswordRestrictions) (string, Metadata, error) {
+	p.start.Do(func() {
+		queue := config.ReadableQueue
+		if queue == 0 {
+			queue = defaultReadableQueue
+		}
+		p.jobs = make(chan generationJob, queue)
+		for i := 0; i < readableWorkers(); i++ {
+			go func() {
+				for job := range p.jobs {
+					readablePoolQueued.Add(-1)
+					job.result <- runJob(job)
+				}
+			}()
+		}
+	})
+	job := generationJob{restrictions: restrictions, result: make(chan generationResult, 1)}
+	readablePoolQueued.Add(1)
+	select {
+	case p.jobs <- job:
+	default:
+		readablePoolQueued.Add(-1)
+		readablePoolRejected.Add(1)
+		return "", Metadata{Strategy: strategyName(restrictions)}, errReadablePoolFull
+	}
+	result := <-job.result
+	return result.password, result.metadata, result.err
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+type Preset struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Parameters  string `json:"parameters"`
+}
+
+type PresetsResponse struct {
+	Error   string   `json:"error"`
+	Presets []Preset `json:"presets"`
+}
+
+// presets expand the preset parameter to the parameters of a standard. New
+// ones only need an entry here.
+var presets = map[string]Preset{
+	"nist-800-63b": {
+		Description: "NIST SP 800-63B memorized secrets: at least 15 characters and no composition rules",
+		Parameters:  "minLength=15&maxLength=20",
+	},
+	"pci-dss": {
+		Description: "PCI DSS 4.0 requirement 8.3.6: at least 12 characters with letters and digits",
+		Parameters:  "minLength=12&maxLength=16&minLetters=1&minDigits=1",
+	},
+	"windows-ad": {
+		Description: "Active Directory password complexity with a minimum length of 14 characters",
+		Parameters:  "adComplexity=true&minLength=14&maxLength=16",
+	},
+}
+
+func presetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// mergePreset adds the parameters of the preset parameter to query, the
+// ones given explicitly take precedence.
+func mergePreset(query url.Values) (url.Values, []string, error) {
+	name := query.Get("preset")
+	if name == "" {
+		return query, nil, nil
+	}
+	preset, ok := presets[strings.ToLower(name)]
+	if !ok {
+		return nil, nil, fmt.Errorf("Parameter preset has to be one of %s, not %q", strings.Join(presetNames(), ", "), name)
+	}
+	parameters, err := url.ParseQuery(preset.Parameters)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Preset %s is invalid", name)
+	}
+	merged, warnings := mergeParameters(query, parameters, "preset")
+	return merged, warnings, nil
+}
+
+// mergeParameters adds parameters to query unless it has them already,
+// expressions of both have to be satisfied.
+func mergeParameters(query url.Values, parameters url.Values, source string) (url.Values, []string) {
+	var warnings []string
+	merged := url.Values{}
+	for key, values := range query {
+		merged[key] = values
+	}
+	for key, values := range parameters {
+		switch {
+		case key == "expression" && merged.Get(key) != "":
+			merged.Set(key, "("+merged.Get(key)+") && "+values[0])
+		case !merged.Has(key):
+			merged[key] = values
+		default:
+			warnings = append(warnings, fmt.Sprintf("Parameter %s from %s overridden by the request", key, source))
+		}
+	}
+	sort.Strings(warnings)
+	return merged, warnings
+}
+
+func handlePresets(w http.ResponseWriter, r *http.Request) {
+	response := PresetsResponse{Presets: make([]Preset, 0, len(presets))}
+	for _, name := range presetNames() {
+		preset := presets[name]
+		preset.Name = name
+		response.Presets = append(response.Presets, preset)
+	}
+	writeResponse(w, r, 200, response)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type clientIPKey struct{}
+
+type trustedProxies []*net.IPNet
+
+func parseTrustedProxies(value string) (trustedProxies, error) {
+	var proxies trustedProxies
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("Invalid trusted proxy %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid trusted proxy %q", entry)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+func (proxies trustedProxies) trusts(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range proxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func forwardedFor(r *http.Request) []string {
+	var addresses []string
+	if forwarded := r.Header.Values("Forwarded"); len(forwarded) > 0 {
+		for _, element := range strings.Split(strings.Join(forwarded, ","), ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if !found || !strings.EqualFold(key, "for") {
+					continue
+				}
+				value = strings.Trim(value, "\"")
+				if host, _, err := net.SplitHostPort(value); err == nil {
+					value = host
+				}
+				addresses = append(addresses, strings.Trim(value, "[]"))
+			}
+		}
+		return addresses
+	}
+	for _, element := range strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			addresses = append(addresses, element)
+		}
+	}
+	return addresses
+}
+
+func (proxies trustedProxies) resolveClientIP(r *http.Request) string {
+	client := remoteHost(r)
+	if !proxies.trusts(client) {
+		return client
+	}
+	addresses := forwardedFor(r)
+	for i := len(addresses) - 1; i >= 0; i-- {
+		if net.ParseIP(addresses[i]) == nil {
+			break
+		}
+		client = addresses[i]
+		if !proxies.trusts(client) {
+			break
+		}
+	}
+	return client
+}
+
+func (proxies trustedProxies) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), clientIPKey{}, proxies.resolveClientIP(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteHost(r)
+}
@@ -0,0 +1,66 @@
+package random
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"errors"
+	"sync"
+)
+
+const bufferSize = 512
+
+// Entropy is read from crypto/rand in batches and handed out 8 bytes at a time.
+var source struct {
+	mu     sync.Mutex
+	buffer [bufferSize]byte
+	pos    int
+}
+
+func init() {
+	source.pos = bufferSize
+}
+
+func uint64n() (uint64, error) {
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	if source.pos+8 > bufferSize {
+		if _, err := cryptorand.Read(source.buffer[:]); err != nil {
+			return 0, err
+		}
+		source.pos = 0
+	}
+	v := binary.LittleEndian.Uint64(source.buffer[source.pos:])
+	source.pos += 8
+	return v, nil
+}
+
+// Intn returns a uniformly distributed integer in [0, n). Values below
+// 2^64 mod n are rejected, so every remainder is equally likely.
+func Intn(n int) (int, error) {
+	if n <= 0 {
+		return 0, errors.New("random: n has to be positive")
+	}
+	bound := uint64(n)
+	threshold := -bound % bound
+	for {
+		v, err := uint64n()
+		if err != nil {
+			return 0, err
+		}
+		if v >= threshold {
+			return int(v % bound), nil
+		}
+	}
+}
+
+// PRNG adapts Intn to the math/rand style interface of gomarkov. It panics
+// when the system random source fails, which it can't report otherwise.
+type PRNG struct{}
+
+func (PRNG) Intn(n int) int {
+	i, err := Intn(n)
+	if err != nil {
+		panic(err)
+	}
+	return i
+}
@@ -0,0 +1,35 @@
+package random
+
+import (
+	"testing"
+
+	"password_gen/random/randomtest"
+)
+
+func TestIntnUniform(t *testing.T) {
+	for _, n := range []int{2, 3, 7, 10, 26, 27, 62, 94, 100, 1000} {
+		samples := 2000 * n
+		counts := make([]int, n)
+		for i := 0; i < samples; i++ {
+			v, err := Intn(n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v < 0 || v >= n {
+				t.Fatalf("Intn(%d) returned %d", n, v)
+			}
+			counts[v]++
+		}
+		if statistic, limit := randomtest.ChiSquare(counts, samples), randomtest.ChiSquareLimit(n-1); statistic > limit {
+			t.Errorf("Intn(%d) isn't uniform: chi-square %.1f over %d samples, more than %.1f", n, statistic, samples, limit)
+		}
+	}
+}
+
+func TestIntnRejectsNonPositive(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		if _, err := Intn(n); err == nil {
+			t.Errorf("Intn(%d) didn't fail", n)
+		}
+	}
+}
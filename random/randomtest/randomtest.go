@@ -0,0 +1,26 @@
+// Package randomtest provides statistics for testing that random values are
+// uniform.
+package randomtest
+
+import "math"
+
+// ChiSquare returns the chi-square statistic of counts of samples expected to
+// be spread uniformly.
+func ChiSquare(counts []int, samples int) float64 {
+	expected := float64(samples) / float64(len(counts))
+	statistic := 0.0
+	for _, count := range counts {
+		deviation := float64(count) - expected
+		statistic += deviation * deviation / expected
+	}
+	return statistic
+}
+
+// ChiSquareLimit is a critical value of the chi-square distribution with df
+// degrees of freedom which uniform samples exceed with a probability of
+// about one in a million, by the Wilson-Hilferty approximation.
+func ChiSquareLimit(df int) float64 {
+	const z = 4.75
+	k := float64(df)
+	return k * math.Pow(1-2/(9*k)+z*math.Sqrt(2/(9*k)), 3)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"expvar"
+	"log"
+	"net/http"
+	"password_gen/markov_chain"
+)
+
+func init() {
+	expvar.Publish("model_degraded", expvar.Func(func() any {
+		if markov_chain.DescribeModel().Error != "" {
+			return 1
+		}
+		return 0
+	}))
+}
+
+type ReadinessStatus struct {
+	Error    string   `json:"error"`
+	Ready    bool     `json:"ready"`
+	Degraded []string `json:"degraded,omitempty"`
+}
+
+// warnModelUnavailable logs that readable passwords are generated by the
+// syllable engine until the model can be read.
+func warnModelUnavailable() {
+	if info := markov_chain.DescribeModel(); info.Error != "" {
+		log.Printf("Model %s can't be read, readable passwords fall back to the syllable engine: %s", info.Path, info.Error)
+	}
+}
+
+// handleReadiness reports whether generation requests are served, and which
+// features are degraded while they are.
+func handleReadiness(w http.ResponseWriter, r *http.Request) {
+	status := ReadinessStatus{Ready: true}
+	if info := markov_chain.DescribeModel(); info.Error != "" {
+		status.Degraded = append(status.Degraded, "model: "+info.Error)
+	} else if modelStale(info) {
+		status.Degraded = append(status.Degraded, modelAgeProblem(info))
+	}
+	if maintenance.Load() && config.MaintenanceMessage != "" {
+		status.Ready = false
+		status.Error = config.MaintenanceMessage
+		writeResponse(w, r, http.StatusServiceUnavailable, status)
+		return
+	}
+	writeResponse(w, r, 200, status)
+}
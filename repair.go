@@ -0,0 +1,103 @@
+package main
+
+import (
+	"password_gen/random"
+	"strings"
+	"unicode"
+)
+
+// characterClass is the group of ch, letters of unicodeRanges belonging to
+// the letters of their case.
+func characterClass(ch rune) string {
+	switch {
+	case unicode.IsUpper(ch):
+		return UpperLetters
+	case unicode.IsLetter(ch):
+		return Letters
+	case isDigit(ch):
+		return Digits
+	default:
+		return SpecialChars
+	}
+}
+
+func (restrictions PasswordRestrictions) permitted(ch rune) bool {
+	return !strings.ContainsRune(restrictions.excluded(), ch) && restrictions.allowed(string(ch))
+}
+
+// repairCharacters replaces characters excluded by excludeChars or outside
+// allowedChars, e.g. ones introduced by the case flags or the markov chain,
+// with permitted characters of the same class instead of discarding the
+// whole candidate.
+func repairCharacters(password string, restrictions PasswordRestrictions) (string, error) {
+	repaired := []rune(password)
+	start, end := restrictions.generated(len(repaired))
+	count := 0
+	for i := start; i < end; i++ {
+		if repaired[i] >= 0x80 || restrictions.permitted(repaired[i]) {
+			continue
+		}
+		charset := restrictions.classCharset(repaired[i])
+		if charset == "" {
+			charset = restrictions.baseCharset()
+		}
+		if charset == "" {
+			continue
+		}
+		ch, err := randomRune(charset)
+		if err != nil {
+			return "", err
+		}
+		repaired[i] = ch
+		count++
+	}
+	if count > 0 {
+		recordRepair("characters", count)
+	}
+	return string(repaired), nil
+}
+
+// repairCase flips the case of letters the other case can spare until
+// minUpperCase and minLowerCase are met again.
+func repairCase(password string, restrictions PasswordRestrictions) (string, error) {
+	repaired := []rune(password)
+	start, end := restrictions.generated(len(repaired))
+	for _, groups := range []struct {
+		need       int
+		needed     string
+		spare      int
+		sparedFrom string
+	}{
+		{restrictions.MinUpperCase, restrictions.upper(), restrictions.MinLowerCase, restrictions.lower()},
+		{restrictions.MinLowerCase, restrictions.lower(), restrictions.MinUpperCase, restrictions.upper()},
+	} {
+		missing := groups.need - countCharset(string(repaired), groups.needed)
+		spare := countCharset(string(repaired[start:end]), groups.sparedFrom) - groups.spare
+		var candidates []int
+		for i := start; i < end; i++ {
+			if strings.ContainsRune(groups.sparedFrom, repaired[i]) && flipCase(repaired[i]) != repaired[i] && restrictions.permitted(flipCase(repaired[i])) {
+				candidates = append(candidates, i)
+			}
+		}
+		flips := 0
+		for ; flips < missing && flips < spare && len(candidates) > 0; flips++ {
+			j, err := random.Intn(len(candidates))
+			if err != nil {
+				return "", err
+			}
+			repaired[candidates[j]] = flipCase(repaired[candidates[j]])
+			candidates = append(candidates[:j], candidates[j+1:]...)
+		}
+		if flips > 0 {
+			recordRepair("caseMode", flips)
+		}
+	}
+	return string(repaired), nil
+}
+
+func flipCase(ch rune) rune {
+	if unicode.IsUpper(ch) {
+		return unicode.ToLower(ch)
+	}
+	return unicode.ToUpper(ch)
+}
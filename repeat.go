@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// resolveRepeats folds noRepeatChars into maxCharOccurrences.
+func resolveRepeats(restrictions *PasswordRestrictions, query url.Values) error {
+	if !restrictions.NoRepeatChars {
+		return nil
+	}
+	if query.Has("maxCharOccurrences") && restrictions.MaxCharOccurrences != 1 {
+		return fmt.Errorf("Parameters noRepeatChars and maxCharOccurrences (%d) can't be combined", restrictions.MaxCharOccurrences)
+	}
+	restrictions.MaxCharOccurrences = 1
+	return nil
+}
+
+func (restrictions PasswordRestrictions) limitsRepeats() bool {
+	return restrictions.MaxCharOccurrences > 0 && restrictions.Mode == "" && restrictions.PassphrasePattern == "" && restrictions.Pattern == ""
+}
+
+// repeatCapacity is the number of characters a password can have without
+// any of them occurring more than maxCharOccurrences times.
+func (restrictions PasswordRestrictions) repeatCapacity() int {
+	chars := restrictions.baseCharset()
+	for _, requirement := range groupRequirements(restrictions) {
+		if requirement.count > 0 {
+			chars += requirement.charset
+		}
+	}
+	return utf8.RuneCountInString(uniqueChars(chars)) * restrictions.MaxCharOccurrences
+}
+
+func overusedChars(password string, limit int) bool {
+	occurrences := map[rune]int{}
+	for _, r := range password {
+		occurrences[r]++
+		if occurrences[r] > limit {
+			return true
+		}
+	}
+	return false
+}
+
+// repairRepeats replaces characters occurring more than maxCharOccurrences
+// times with unused characters of the same class, or of any class when the
+// same one is used up.
+func repairRepeats(password string, restrictions PasswordRestrictions) (string, error) {
+	limit := restrictions.MaxCharOccurrences
+	repaired := []rune(password)
+	occurrences := map[rune]int{}
+	for _, ch := range repaired {
+		occurrences[ch]++
+	}
+	available := func(charset string) string {
+		var chars strings.Builder
+		for _, ch := range charset {
+			if occurrences[ch] < limit {
+				chars.WriteRune(ch)
+			}
+		}
+		return chars.String()
+	}
+	start, end := restrictions.generated(len(repaired))
+	count := 0
+	for i := start; i < end; i++ {
+		if occurrences[repaired[i]] <= limit {
+			continue
+		}
+		charset := available(restrictions.classCharset(repaired[i]))
+		if charset == "" {
+			charset = restrictions.spacedCharset(repaired, i, available(restrictions.baseCharset()))
+		}
+		if charset == "" {
+			break
+		}
+		ch, err := randomRune(charset)
+		if err != nil {
+			return "", err
+		}
+		occurrences[repaired[i]]--
+		repaired[i] = ch
+		occurrences[ch]++
+		count++
+	}
+	if count > 0 {
+		recordRepair("repeats", count)
+	}
+	return string(repaired), nil
+}
+
+func checkRepeats(password string, restrictions PasswordRestrictions) error {
+	if overusedChars(password, restrictions.MaxCharOccurrences) {
+		return violation("repeats", errors.New("Generated password repeats characters more than maxCharOccurrences allows, try again"))
+	}
+	for _, requirement := range groupRequirements(restrictions) {
+		if countCharset(password, requirement.counts) < requirement.count {
+			return violation("repeats", fmt.Errorf("Generated password doesn't satisfy %s after replacing repeated characters, try again", requirement.parameter))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const asciiSpecialChars = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+type RulesResponse struct {
+	Error         string                `json:"error"`
+	Format        string                `json:"format,omitempty"`
+	Policy        string                `json:"policy,omitempty"`
+	PasswordRules string                `json:"passwordRules,omitempty"`
+	Warnings      []string              `json:"warnings,omitempty"`
+	PolicyApplied *PasswordRestrictions `json:"policyApplied,omitempty"`
+}
+
+var passwordRulesProperty = regexp.MustCompile(`(?i)^\s*(required|allowed|max-consecutive|minlength|maxlength)\s*:`)
+
+// passwordRulesClasses are the named character classes of the passwordrules
+// attribute and the parameters requiring one of them.
+var passwordRulesClasses = map[string]struct {
+	chars     string
+	parameter string
+}{
+	"upper":           {UpperLetters, "minUpperCase"},
+	"lower":           {Letters, "minLowerCase"},
+	"digit":           {Digits, "minDigits"},
+	"special":         {asciiSpecialChars, "minSpecialChars"},
+	"ascii-printable": {Letters + UpperLetters + Digits + asciiSpecialChars, ""},
+	"unicode":         {Letters + UpperLetters + Digits + asciiSpecialChars, ""},
+}
+
+type passwordRulesClass struct {
+	name  string
+	chars string
+}
+
+func parseRulesClasses(value string) ([]passwordRulesClass, error) {
+	var classes []passwordRulesClass
+	for value = strings.TrimSpace(value); value != ""; value = strings.TrimLeft(value, ", ") {
+		if value[0] == '[' {
+			end := -1
+			if len(value) > 2 {
+				end = strings.IndexByte(value[2:], ']')
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("Custom character class %q isn't closed", value)
+			}
+			chars := value[1 : end+2]
+			classes = append(classes, passwordRulesClass{chars: strings.Map(func(r rune) rune {
+				if r < '!' || r > '~' {
+					return -1
+				}
+				return r
+			}, chars)})
+			value = value[end+3:]
+			continue
+		}
+		name, rest, _ := strings.Cut(value, ",")
+		name = strings.ToLower(strings.TrimSpace(name))
+		class, ok := passwordRulesClasses[name]
+		if !ok {
+			return nil, fmt.Errorf("Character class %q is unknown", name)
+		}
+		classes = append(classes, passwordRulesClass{name: name, chars: class.chars})
+		value = rest
+	}
+	return classes, nil
+}
+
+// quoteExpression quotes s as a string literal of the expression language.
+func quoteExpression(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// parsePasswordRules converts the passwordrules attribute format, e.g.
+// "required: upper; required: digit; allowed: lower; minlength: 12", to
+// request parameters.
+func parsePasswordRules(rules string) (url.Values, []string, error) {
+	query := url.Values{}
+	var warnings, expressions []string
+	required := map[string]int{}
+	allowed := ""
+	allowedSet := false
+
+	for _, rule := range strings.Split(rules, ";") {
+		if strings.TrimSpace(rule) == "" {
+			continue
+		}
+		name, value, found := strings.Cut(rule, ":")
+		if !found {
+			return nil, nil, fmt.Errorf("Rule %q has to be a name: value pair", strings.TrimSpace(rule))
+		}
+		name, value = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(value)
+		switch name {
+		case "minlength", "maxlength":
+			length, err := strconv.Atoi(value)
+			if err != nil || length < 0 {
+				return nil, nil, fmt.Errorf("Rule %s has to be a non negative number", name)
+			}
+			query.Set(strings.Replace(name, "length", "Length", 1), value)
+		case "max-consecutive":
+			consecutive, err := strconv.Atoi(value)
+			if err != nil || consecutive < 1 {
+				return nil, nil, errors.New("Rule max-consecutive has to be a positive number")
+			}
+			query.Set("maxConsecutiveIdentical", value)
+		case "required", "allowed":
+			classes, err := parseRulesClasses(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("Rule %s is invalid: %w", name, err)
+			}
+			for _, class := range classes {
+				allowed += class.chars
+				if class.name == "unicode" {
+					warnings = append(warnings, "Character class unicode isn't supported, ascii-printable was used instead")
+				}
+			}
+			allowedSet = true
+			if name == "allowed" {
+				continue
+			}
+			if len(classes) == 1 && passwordRulesClasses[classes[0].name].parameter != "" {
+				required[passwordRulesClasses[classes[0].name].parameter]++
+				continue
+			}
+			if len(classes) == 2 && (classes[0].name+","+classes[1].name == "upper,lower" || classes[0].name+","+classes[1].name == "lower,upper") {
+				required["minLetters"]++
+				continue
+			}
+			var counts []string
+			for _, class := range classes {
+				switch class.name {
+				case "":
+					for _, ch := range uniqueChars(class.chars) {
+						counts = append(counts, "count("+quoteExpression(string(ch))+")")
+					}
+				case "upper", "lower":
+					counts = append(counts, "count("+class.name+")")
+				case "digit":
+					counts = append(counts, "count(digits)")
+				case "special":
+					counts = append(counts, "count(specials)")
+				default:
+					// Any character satisfies ascii-printable.
+					counts = nil
+				}
+				if counts == nil {
+					break
+				}
+			}
+			if len(counts) > 0 {
+				expressions = append(expressions, strings.Join(counts, " + ")+" >= 1")
+			}
+		default:
+			warnings = append(warnings, fmt.Sprintf("Rule %s is unknown and was ignored", name))
+		}
+	}
+
+	for parameter, count := range required {
+		query.Set(parameter, strconv.Itoa(count))
+	}
+	if len(expressions) > 0 {
+		query.Set("expression", strings.Join(expressions, " && "))
+	}
+	if allowedSet {
+		allowed = uniqueChars(allowed)
+		if len(allowed) < len(Letters+UpperLetters+Digits+asciiSpecialChars) {
+			query.Set("allowedChars", allowed)
+		}
+	}
+	return query, warnings, nil
+}
+
+var (
+	rulesNumber     = `(\d+|an?|one|two|three|four|five|six|seven|eight|nine|ten)`
+	rulesNumbers    = map[string]int{"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10}
+	rulesLengthSpan = regexp.MustCompile(`(?i)\b(\d+)\s*(?:-|–|to|and)\s*(\d+)\s*(?:characters|chars)`)
+	rulesMinLength  = regexp.MustCompile(`(?i)\b(?:at least|minimum(?: of)?|min\.?|no fewer than|not less than)\s*` + rulesNumber + `\s*(?:characters|chars)\b`)
+	rulesMaxLength  = regexp.MustCompile(`(?i)\b(?:at most|maximum(?: of)?|max\.?|no more than|not more than|up to)\s*` + rulesNumber + `\s*(?:characters|chars)\b`)
+	rulesGroups     = []struct {
+		parameter string
+		re        *regexp.Regexp
+	}{
+		{"minUpperCase", regexp.MustCompile(`(?i)\b` + rulesNumber + `\s*(?:upper[- ]?case|capital)(?: letters?| characters?)?\b`)},
+		{"minLowerCase", regexp.MustCompile(`(?i)\b` + rulesNumber + `\s*lower[- ]?case(?: letters?| characters?)?\b`)},
+		{"minDigits", regexp.MustCompile(`(?i)\b` + rulesNumber + `\s*(?:digits?|numbers?|numerals?|numeric characters?)\b`)},
+		{"minSpecialChars", regexp.MustCompile(`(?i)\b` + rulesNumber + `\s*(?:special characters?|special chars?|symbols?|non-alphanumeric characters?|punctuation (?:marks?|characters?))`)},
+		{"minLetters", regexp.MustCompile(`(?i)\b` + rulesNumber + `\s*(?:letters?|alphabetic characters?)\b`)},
+	}
+	rulesCaseLetters = regexp.MustCompile(`(?i)(upper|lower)[- ]?case\s+letters?$`)
+	rulesSentence    = regexp.MustCompile(`[.;\n]+\s*|,\s+(?:and\s+)?`)
+)
+
+func rulesCount(s string) int {
+	if count, ok := rulesNumbers[strings.ToLower(s)]; ok {
+		return count
+	}
+	count, _ := strconv.Atoi(s)
+	return count
+}
+
+// parseRulesText picks lengths and group minimums out of a human readable
+// password policy, e.g. "8 to 64 characters, at least one uppercase letter
+// and two digits", warning about the parts it doesn't understand.
+func parseRulesText(text string) (url.Values, []string) {
+	query := url.Values{}
+	var warnings []string
+	for _, sentence := range rulesSentence.Split(text, -1) {
+		sentence = strings.TrimSpace(sentence)
+		if sentence == "" {
+			continue
+		}
+		recognized := false
+		if match := rulesLengthSpan.FindStringSubmatch(sentence); match != nil {
+			query.Set("minLength", match[1])
+			query.Set("maxLength", match[2])
+			recognized = true
+		}
+		if match := rulesMinLength.FindStringSubmatch(sentence); match != nil {
+			query.Set("minLength", strconv.Itoa(rulesCount(match[1])))
+			recognized = true
+		}
+		if match := rulesMaxLength.FindStringSubmatch(sentence); match != nil {
+			query.Set("maxLength", strconv.Itoa(rulesCount(match[1])))
+			recognized = true
+		}
+		for _, group := range rulesGroups {
+			for _, match := range group.re.FindAllStringSubmatch(sentence, -1) {
+				if group.parameter == "minLetters" && rulesCaseLetters.MatchString(match[0]) {
+					continue
+				}
+				count, _ := strconv.Atoi(query.Get(group.parameter))
+				query.Set(group.parameter, strconv.Itoa(max(count, rulesCount(match[1]))))
+				recognized = true
+			}
+		}
+		if !recognized {
+			warnings = append(warnings, fmt.Sprintf("Rule %q isn't recognized and was ignored", sentence))
+		}
+	}
+	return query, warnings
+}
+
+func handlePasswordRules(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		handleError(w, r, bodyError(err, "Body can't be read"))
+		return
+	}
+	text := strings.TrimSpace(string(body))
+	if text == "" {
+		handleError(w, r, errors.New("Body has to contain the password rules"))
+		return
+	}
+
+	var query url.Values
+	var warnings []string
+	format := "text"
+	if passwordRulesProperty.MatchString(text) {
+		format = "passwordrules"
+		query, warnings, err = parsePasswordRules(text)
+		if err != nil {
+			handleError(w, r, err)
+			return
+		}
+	} else {
+		query, warnings = parseRulesText(text)
+	}
+	if len(query) == 0 {
+		handleError(w, r, errors.New("Password rules don't contain any supported restriction"))
+		return
+	}
+
+	restrictions, err := parseRestrictions(query)
+	if err != nil {
+		writeResponse(w, r, 400, RulesResponse{Error: err.Error(), Format: format, Policy: query.Encode(), Warnings: warnings})
+		return
+	}
+	warnings = append(warnings, restrictions.warnings...)
+	writeResponse(w, r, 200, RulesResponse{Error: "", Format: format, Policy: query.Encode(), PasswordRules: formatPasswordRules(restrictions), Warnings: warnings, PolicyApplied: &restrictions})
+}
+
+// mergePasswordRules adds the parameters of the passwordRules parameter to
+// query, the ones given explicitly take precedence.
+func mergePasswordRules(query url.Values) (url.Values, []string, error) {
+	if query.Get("passwordRules") == "" {
+		return query, nil, nil
+	}
+	parsed, warnings, err := parsePasswordRules(query.Get("passwordRules"))
+	if err != nil {
+		return nil, nil, errors.New("Parameter passwordRules is invalid: " + err.Error())
+	}
+	merged, mergeWarnings := mergeParameters(query, parsed, "passwordRules")
+	return merged, append(warnings, mergeWarnings...), nil
+}
+
+// passwordRulesChars formats chars as a passwordrules class list, using
+// the named classes it contains completely.
+func passwordRulesChars(chars string) string {
+	var classes []string
+	rest := chars
+	for _, name := range []string{"upper", "lower", "digit", "special"} {
+		class := passwordRulesClasses[name].chars
+		if strings.Trim(class, chars) == "" {
+			classes = append(classes, name)
+			rest = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(class, r) {
+					return -1
+				}
+				return r
+			}, rest)
+		}
+	}
+	if len(classes) == 4 {
+		return "ascii-printable"
+	}
+	if rest != "" {
+		// ] has to come first and - last in a custom class.
+		custom := strings.NewReplacer("]", "", "-", "").Replace(rest)
+		if strings.Contains(rest, "]") {
+			custom = "]" + custom
+		}
+		if strings.Contains(rest, "-") {
+			custom += "-"
+		}
+		classes = append(classes, "["+custom+"]")
+	}
+	return strings.Join(classes, ", ")
+}
+
+// formatPasswordRules describes the restrictions in the passwordrules
+// attribute format. It only has at least one of a class, so larger group
+// minimums are described as a single requirement.
+func formatPasswordRules(restrictions PasswordRestrictions) string {
+	var rules []string
+	for _, group := range []struct {
+		count int
+		class string
+	}{
+		{restrictions.MinUpperCase, "upper"},
+		{restrictions.MinLowerCase, "lower"},
+		{restrictions.MinDigits, "digit"},
+		{restrictions.MinSpecialChars, "special"},
+		{restrictions.MinLetters, "upper, lower"},
+	} {
+		if group.count > 0 {
+			rules = append(rules, "required: "+group.class)
+		}
+	}
+	chars := uniqueChars(restrictions.baseCharset() + restrictions.charset(UpperLetters))
+	if restrictions.AllUpperCase {
+		chars = strings.ToUpper(chars)
+	}
+	if restrictions.AllLowerCase {
+		chars = strings.ToLower(chars)
+	}
+	rules = append(rules, "allowed: "+passwordRulesChars(uniqueChars(chars)))
+	if restrictions.MinLength > 0 {
+		rules = append(rules, "minlength: "+strconv.Itoa(restrictions.MinLength))
+	}
+	rules = append(rules, "maxlength: "+strconv.Itoa(restrictions.MaxLength))
+	if restrictions.MaxConsecutiveIdentical > 0 {
+		rules = append(rules, "max-consecutive: "+strconv.Itoa(restrictions.MaxConsecutiveIdentical))
+	}
+	return strings.Join(rules, "; ")
+}
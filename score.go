@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"password_gen/dictionary"
+	"password_gen/markov_chain"
+	"strconv"
+	"strings"
+
+	"github.com/nbutton23/zxcvbn-go"
+)
+
+// zxcvbn is cubic in the password length, longer passwords are scored by
+// their beginning, which is enough to reach the top score.
+const zxcvbnMaxLength = 100
+
+func zxcvbnScore(password string, userInputs ...string) int {
+	if runes := []rune(password); len(runes) > zxcvbnMaxLength {
+		password = string(runes[:zxcvbnMaxLength])
+	}
+	return zxcvbn.PasswordStrength(password, userInputs).Score
+}
+
+func hashcatRule(crack *dictionary.Crack) string {
+	if crack == nil {
+		return ""
+	}
+	return crack.Word + " " + crack.Rule
+}
+
+func readPasswords(input io.Reader) ([]string, error) {
+	var passwords []string
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		if password := scanner.Text(); password != "" {
+			passwords = append(passwords, password)
+		}
+	}
+	return passwords, scanner.Err()
+}
+
+func scorePasswords(input io.Reader, output io.Writer, restrictions *PasswordRestrictions) error {
+	passwords, err := readPasswords(input)
+	if err != nil {
+		return err
+	}
+	probabilities, err := markov_chain.PasswordProbabilities(passwords)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(output)
+	header := []string{"password", "probability", "zxcvbn", "dictionaryWords", "effectiveEntropyBits", "hashcatRule"}
+	if restrictions != nil {
+		header = append(header, "verdict", "violations")
+	}
+	header = append(header, "suggestions")
+	writer.Write(header)
+	for i, password := range passwords {
+		analysis := dictionary.Analyze(password)
+		words := make([]string, 0, len(analysis.Matches))
+		for _, match := range analysis.Matches {
+			words = append(words, match.Word)
+		}
+		score := ScoreResponse{
+			Zxcvbn:     zxcvbnScore(password),
+			Dictionary: &analysis,
+			Crack:      dictionary.Crackability(password, dictionary.CrackTopN),
+		}
+		row := []string{
+			password,
+			strconv.FormatFloat(probabilities[i], 'f', 4, 64),
+			strconv.Itoa(score.Zxcvbn),
+			strings.Join(words, " "),
+			strconv.FormatFloat(analysis.EffectiveEntropyBits, 'f', 1, 64),
+			hashcatRule(score.Crack),
+		}
+		if restrictions != nil {
+			violations := policyViolations(password, *restrictions)
+			verdict := "pass"
+			if len(violations) > 0 {
+				verdict = "fail"
+			}
+			row = append(row, verdict, strings.Join(violations, "; "))
+		}
+		row = append(row, strings.Join(suggestImprovements(password, score), "; "))
+		writer.Write(row)
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"password_gen/dictionary"
+	"password_gen/keyboard"
+	"password_gen/markov_chain"
+)
+
+type ScoreRequest struct {
+	Password string `json:"password"`
+}
+
+type ScoreResponse struct {
+	Error       string               `json:"error"`
+	Probability float64              `json:"probability"`
+	Zxcvbn      int                  `json:"zxcvbn"`
+	Dictionary  *dictionary.Analysis `json:"dictionary,omitempty"`
+	Crack       *dictionary.Crack    `json:"crack,omitempty"`
+	Walks       []keyboard.Walk      `json:"keyboardWalks,omitempty"`
+	Violations  []string             `json:"violations,omitempty"`
+	Breached    *bool                `json:"breached,omitempty"`
+	Suggestions []string             `json:"suggestions,omitempty"`
+}
+
+func parseScoreRequest(r *http.Request) (string, error) {
+	var request ScoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return "", bodyError(err, "Body has to be a json object with a password")
+	}
+	if request.Password == "" {
+		return "", errors.New("Parameter password can't be empty")
+	}
+	if config.MaxParameterLength > 0 && len(request.Password) > config.MaxParameterLength {
+		return "", fmt.Errorf("Parameter password can't be longer than %d characters", config.MaxParameterLength)
+	}
+	return request.Password, nil
+}
+
+func scorePassword(password string, restrictions *PasswordRestrictions) (ScoreResponse, error) {
+	probabilities, err := markov_chain.PasswordProbabilities([]string{password})
+	if err != nil {
+		return ScoreResponse{}, errors.New("Password can't be scored, try again later")
+	}
+	analysis := dictionary.Analyze(password)
+	var userInputs []string
+	if restrictions != nil {
+		userInputs = restrictions.userTokens
+	}
+	response := ScoreResponse{
+		Probability: probabilities[0],
+		Zxcvbn:      zxcvbnScore(password, userInputs...),
+		Dictionary:  &analysis,
+		Crack:       dictionary.Crackability(password, dictionary.CrackTopN),
+		Walks:       keyboard.Walks(password, keyboard.MinWalkLength),
+	}
+	if restrictions != nil {
+		response.Violations = policyViolations(password, *restrictions)
+	}
+	for _, term := range blockedTerms(password) {
+		response.Violations = append(response.Violations, fmt.Sprintf("contains the blocked term '%s'", term))
+	}
+	if _, noop := breachChecker.(noopBreachChecker); !noop {
+		breached, err := breachChecker.Breached(password)
+		if err != nil {
+			return ScoreResponse{}, errBreachCheck
+		}
+		response.Breached = &breached
+	}
+	response.Suggestions = suggestImprovements(password, response)
+	return response, nil
+}
+
+func handlePasswordScore(w http.ResponseWriter, r *http.Request) {
+	var restrictions *PasswordRestrictions
+	query, err := requestQuery(r)
+	if err != nil {
+		writeResponse(w, r, errorStatus(err), ScoreResponse{Error: err.Error()})
+		return
+	}
+	query.Del("fields")
+	if len(query) > 0 {
+		parsed, err := parseRestrictions(query)
+		if err != nil {
+			writeResponse(w, r, errorStatus(err), ScoreResponse{Error: err.Error()})
+			return
+		}
+		restrictions = &parsed
+	}
+	password, err := parseScoreRequest(r)
+	if err != nil {
+		writeResponse(w, r, errorStatus(err), ScoreResponse{Error: err.Error()})
+		return
+	}
+	response, err := scorePassword(password, restrictions)
+	if errors.Is(err, errBreachCheck) {
+		writeResponse(w, r, errorStatus(err), ScoreResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		writeResponse(w, r, 500, ScoreResponse{Error: err.Error()})
+		return
+	}
+	writeResponse(w, r, 200, response)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"sync"
+)
+
+const (
+	minRedactedLength = 4
+	redacted          = "[REDACTED]"
+)
+
+type candidateTracker struct {
+	mu         sync.Mutex
+	candidates []string
+}
+
+func (t *candidateTracker) track(candidate string) {
+	if t == nil || len(candidate) < minRedactedLength {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.candidates = append(t.candidates, candidate)
+}
+
+func (t *candidateTracker) scrub(message string) string {
+	if t == nil {
+		return message
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, candidate := range t.candidates {
+		message = redactSubstrings(message, candidate)
+	}
+	return message
+}
+
+// redactSubstrings replaces every run of message that is a substring of
+// candidate and at least minRedactedLength long.
+func redactSubstrings(message string, candidate string) string {
+	var sb strings.Builder
+	for i := 0; i < len(message); {
+		n := 0
+		for i+n < len(message) && strings.Contains(candidate, message[i:i+n+1]) {
+			n++
+		}
+		if n >= minRedactedLength {
+			sb.WriteString(redacted)
+			i += n
+			continue
+		}
+		sb.WriteByte(message[i])
+		i++
+	}
+	return sb.String()
+}
+
+type scrubbedError struct {
+	err     error
+	tracker *candidateTracker
+}
+
+func (e scrubbedError) Error() string {
+	return e.tracker.scrub(e.err.Error())
+}
+
+func (e scrubbedError) Unwrap() error {
+	return e.err
+}
+
+func scrubError(err error, tracker *candidateTracker) error {
+	if err == nil {
+		return nil
+	}
+	return scrubbedError{err: err, tracker: tracker}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+)
+
+const (
+	defaultSecretBytes = 32
+	maxSecretBytes     = 1024
+)
+
+// secretParameters are ignored by the secret modes on top of the character
+// ones, as their characters are the encoding's and nothing is checked.
+var secretParameters = []string{"excludeChars", "excludeAmbiguous", "allowedChars", "symbols", "caseMode", "expression", "mustMatch", "noKeyboardWalks", "userInputs", "oldPassword", "oldPasswordHash", "rejectDictionaryWords"}
+
+func isSecretMode(mode string) bool {
+	return mode == "hex" || mode == "base64"
+}
+
+func secretMode(restrictions *PasswordRestrictions, query url.Values) error {
+	if restrictions.Bytes == 0 {
+		restrictions.Bytes = defaultSecretBytes
+		restrictions.warn(fmt.Sprintf("Parameter bytes defaulted to %d", defaultSecretBytes))
+	}
+	if restrictions.Bytes < 1 || restrictions.Bytes > maxSecretBytes {
+		return fmt.Errorf("Parameter bytes has to be between 1 and %d", maxSecretBytes)
+	}
+	for _, key := range secretParameters {
+		if query.Has(key) {
+			restrictions.warn(fmt.Sprintf("Parameter %s ignored because mode=%s is set", key, restrictions.Mode))
+		}
+	}
+	return nil
+}
+
+// generateSecret reads bytes straight from crypto/rand and encodes them,
+// for machine secrets such as session or HMAC keys.
+func generateSecret(restrictions PasswordRestrictions) (string, error) {
+	secret := make([]byte, restrictions.Bytes)
+	if _, err := cryptorand.Read(secret); err != nil {
+		return "", err
+	}
+	return encodeSecret(secret, restrictions.Mode), nil
+}
+
+func encodeSecret(secret []byte, mode string) string {
+	if mode == "base64" {
+		return base64.StdEncoding.EncodeToString(secret)
+	}
+	return hex.EncodeToString(secret)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	defaultMaxSequenceLength = 2
+	defaultKeyboardLayout    = "qwerty"
+)
+
+var keyboardLayouts = map[string][]string{
+	"qwerty": {"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"},
+	"qwertz": {"1234567890", "qwertzuiop", "asdfghjkl", "yxcvbnm"},
+	"azerty": {"1234567890", "azertyuiop", "qsdfghjklm", "wxcvbn"},
+}
+
+// resolveSequences validates keyboardLayout and maxSequenceLength and
+// defaults them when noSequences is set.
+func resolveSequences(restrictions *PasswordRestrictions, query url.Values) error {
+	restrictions.KeyboardLayout = strings.ToLower(restrictions.KeyboardLayout)
+	if _, ok := keyboardLayouts[restrictions.KeyboardLayout]; restrictions.KeyboardLayout != "" && !ok {
+		return fmt.Errorf("Parameter keyboardLayout has to be qwerty, qwertz or azerty, not %q", restrictions.KeyboardLayout)
+	}
+	if !restrictions.NoSequences {
+		for _, key := range []string{"maxSequenceLength", "keyboardLayout"} {
+			if query.Has(key) {
+				restrictions.warn(fmt.Sprintf("Parameter %s ignored because noSequences isn't set", key))
+			}
+		}
+		return nil
+	}
+	if restrictions.KeyboardLayout == "" {
+		restrictions.KeyboardLayout = defaultKeyboardLayout
+	}
+	if !query.Has("maxSequenceLength") {
+		restrictions.MaxSequenceLength = defaultMaxSequenceLength
+	}
+	if restrictions.MaxSequenceLength < 2 {
+		return errors.New("Parameter maxSequenceLength has to be at least 2")
+	}
+	return nil
+}
+
+func (restrictions PasswordRestrictions) limitsSequences() bool {
+	return restrictions.NoSequences && restrictions.Mode == "" && restrictions.PassphrasePattern == "" && restrictions.Pattern == ""
+}
+
+func alphabeticStep(a, b rune) int {
+	a, b = toLower(a), toLower(b)
+	sameClass := (isLower(a) && isLower(b)) || (isDigit(a) && isDigit(b))
+	if !sameClass {
+		return 0
+	}
+	return int(b) - int(a)
+}
+
+func keyboardStep(a, b rune, layout string) int {
+	a, b = toLower(a), toLower(b)
+	for _, row := range keyboardLayouts[layout] {
+		i, j := strings.IndexRune(row, a), strings.IndexRune(row, b)
+		if i >= 0 && j >= 0 {
+			return j - i
+		}
+	}
+	return 0
+}
+
+// sequenceSteps tells whether b continues an ascending or descending run
+// from a in the alphabet, the digits or a keyboard row of layout.
+func sequenceSteps(a, b rune, layout string) [4]bool {
+	alphabetic, keyboard := alphabeticStep(a, b), keyboardStep(a, b, layout)
+	return [4]bool{alphabetic == 1, alphabetic == -1, keyboard == 1, keyboard == -1}
+}
+
+// scanSequences walks the password tracking the runs ending at each
+// position and calls fix for generated positions where a run gets
+// longer than maxSequenceLength. fix may replace the character, it returns
+// false to stop the scan.
+func scanSequences(password []rune, restrictions PasswordRestrictions, fix func(i int) bool) {
+	var runs [4]int
+	start, end := restrictions.generated(len(password))
+	for i := 1; i < len(password); i++ {
+		longest := 0
+		for k, continues := range sequenceSteps(password[i-1], password[i], restrictions.KeyboardLayout) {
+			if continues {
+				runs[k]++
+			} else {
+				runs[k] = 0
+			}
+			longest = max(longest, runs[k]+1)
+		}
+		if longest <= restrictions.MaxSequenceLength || i < start || i >= end {
+			continue
+		}
+		if !fix(i) {
+			return
+		}
+		for k, continues := range sequenceSteps(password[i-1], password[i], restrictions.KeyboardLayout) {
+			if !continues {
+				runs[k] = 0
+			}
+		}
+	}
+}
+
+func hasSequences(password string, restrictions PasswordRestrictions) bool {
+	found := false
+	scanSequences([]rune(password), restrictions, func(int) bool {
+		found = true
+		return false
+	})
+	return found
+}
+
+// repairSequences replaces the character making a run too long with one of
+// the same class which doesn't continue any run from its predecessor.
+func repairSequences(password string, restrictions PasswordRestrictions) (string, error) {
+	repaired := []rune(password)
+	count := 0
+	var err error
+	scanSequences(repaired, restrictions, func(i int) bool {
+		var candidates strings.Builder
+		charset := restrictions.classCharset(repaired[i])
+		for _, candidate := range charset {
+			if sequenceSteps(repaired[i-1], candidate, restrictions.KeyboardLayout) != [4]bool{} {
+				continue
+			}
+			if restrictions.MaxCharOccurrences > 0 && strings.Count(string(repaired), string(candidate)) >= restrictions.MaxCharOccurrences {
+				continue
+			}
+			candidates.WriteRune(candidate)
+		}
+		if candidates.Len() == 0 {
+			return true
+		}
+		var ch rune
+		ch, err = randomRune(candidates.String())
+		if err != nil {
+			return false
+		}
+		repaired[i] = ch
+		count++
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+	if count > 0 {
+		recordRepair("sequences", count)
+	}
+	return string(repaired), nil
+}
+
+func isLower(ch rune) bool {
+	return ch >= 'a' && ch <= 'z'
+}
+
+func isDigit(ch rune) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func toLower(ch rune) rune {
+	if ch >= 'A' && ch <= 'Z' {
+		return ch - 'A' + 'a'
+	}
+	return ch
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const maxSessions = 10000
+
+type SessionResponse struct {
+	Error         string                `json:"error"`
+	Session       string                `json:"session"`
+	ExpiresAt     *time.Time            `json:"expiresAt,omitempty"`
+	Warnings      []string              `json:"warnings,omitempty"`
+	PolicyApplied *PasswordRestrictions `json:"policyApplied,omitempty"`
+}
+
+type session struct {
+	restrictions PasswordRestrictions
+	expires      time.Time
+}
+
+// sessionStore keeps the parsed policies of preference sessions, so that
+// clients regenerating candidates don't have them parsed and validated on
+// every request.
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+var sessions = &sessionStore{sessions: map[string]*session{}}
+
+var errSessionsDisabled = statusError{status: http.StatusNotFound, message: "Sessions are disabled, set sessionTTL"}
+
+// policyParameters are the query parameters of PasswordRestrictions.
+var policyParameters = func() map[string]bool {
+	parameters := map[string]bool{}
+	fields := reflect.TypeOf(PasswordRestrictions{})
+	for i := 0; i < fields.NumField(); i++ {
+		if name := fields.Field(i).Tag.Get("schema"); name != "" {
+			parameters[name] = true
+		}
+	}
+	return parameters
+}()
+
+func (s *sessionStore) create(restrictions PasswordRestrictions) (string, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for id, stored := range s.sessions {
+		if now.After(stored.expires) {
+			delete(s.sessions, id)
+		}
+	}
+	if len(s.sessions) >= maxSessions {
+		return "", time.Time{}, statusError{status: http.StatusServiceUnavailable, message: "Too many sessions are open, try again later"}
+	}
+	id := make([]byte, 16)
+	if _, err := cryptorand.Read(id); err != nil {
+		return "", time.Time{}, statusError{status: http.StatusInternalServerError, message: "Session can't be created"}
+	}
+	stored := &session{restrictions: restrictions, expires: now.Add(config.SessionTTL.Duration)}
+	s.sessions[hex.EncodeToString(id)] = stored
+	return hex.EncodeToString(id), stored.expires, nil
+}
+
+// restrictions are the policy of the session, whose expiry is extended by
+// sessionTTL with every use.
+func (s *sessionStore) restrictions(id string) (PasswordRestrictions, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.sessions[id]
+	if !ok || time.Now().After(stored.expires) {
+		delete(s.sessions, id)
+		return PasswordRestrictions{}, statusError{status: http.StatusNotFound, message: "Session doesn't exist or has expired, create a new one"}
+	}
+	stored.expires = time.Now().Add(config.SessionTTL.Duration)
+	restrictions := stored.restrictions
+	restrictions.warnings = slices.Clone(restrictions.warnings)
+	return restrictions, nil
+}
+
+func (s *sessionStore) delete(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.sessions[id]
+	delete(s.sessions, id)
+	return ok
+}
+
+// sessionRestrictions are the restrictions of the session in the query,
+// which can't be combined with policy parameters, or else of the query
+// itself.
+func sessionRestrictions(query url.Values) (PasswordRestrictions, error) {
+	if !query.Has("session") {
+		return parseRestrictions(query)
+	}
+	if config.SessionTTL.Duration <= 0 {
+		return PasswordRestrictions{}, errSessionsDisabled
+	}
+	var combined []string
+	for key := range query {
+		if policyParameters[key] {
+			combined = append(combined, key)
+		}
+	}
+	if len(combined) > 0 {
+		slices.Sort(combined)
+		return PasswordRestrictions{}, fmt.Errorf("Parameter session can't be combined with %s, create a new session to change the policy", strings.Join(combined, ", "))
+	}
+	return sessions.restrictions(query.Get("session"))
+}
+
+func handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	if config.SessionTTL.Duration <= 0 {
+		handleError(w, r, errSessionsDisabled)
+		return
+	}
+	query, err := requestQuery(r)
+	if err != nil {
+		writeResponse(w, r, errorStatus(err), SessionResponse{Error: err.Error()})
+		return
+	}
+	restrictions, err := parseRestrictions(query)
+	if err != nil {
+		writeResponse(w, r, errorStatus(err), SessionResponse{Error: err.Error()})
+		return
+	}
+	id, expires, err := sessions.create(restrictions)
+	if err != nil {
+		writeResponse(w, r, errorStatus(err), SessionResponse{Error: err.Error()})
+		return
+	}
+	writeResponse(w, r, http.StatusCreated, SessionResponse{Session: id, ExpiresAt: &expires, Warnings: restrictions.warnings, PolicyApplied: &restrictions})
+}
+
+func handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	if !sessions.delete(mux.Vars(r)["id"]) {
+		handleError(w, r, statusError{status: http.StatusNotFound, message: "Session doesn't exist or has expired"})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultMinPasswordDistance = 4
+	defaultMaxCommonSubstring  = 3
+	minOldPasswordHashLength   = 5
+)
+
+// headerParameters are the parameters which can't be sent in the query
+// string, which ends up in access logs and browser history, and the headers
+// they are sent in instead. oldPasswordHash can be the whole unsalted sha1 of
+// the old password, which is as good as the password itself.
+var headerParameters = []struct {
+	parameter string
+	header    string
+}{
+	{"oldPassword", "Old-Password"},
+	{"oldPasswordHash", "Old-Password-Hash"},
+}
+
+// requestQuery is the query of r, with oldPassword and oldPasswordHash taken
+// from their headers. They are refused in the query string itself.
+func requestQuery(r *http.Request) (url.Values, error) {
+	query := r.URL.Query()
+	for _, sent := range headerParameters {
+		if query.Has(sent.parameter) {
+			return query, fmt.Errorf("Parameter %s can't be sent in the query string, which ends up in access logs and browser history, send it in the %s header", sent.parameter, sent.header)
+		}
+		if value := r.Header.Get(sent.header); value != "" {
+			query.Set(sent.parameter, value)
+		}
+	}
+	return query, nil
+}
+
+// resolveOldPassword validates oldPasswordHash and defaults the similarity
+// limits when oldPassword is set.
+func resolveOldPassword(restrictions *PasswordRestrictions, query url.Values) error {
+	if restrictions.OldPasswordHash != "" {
+		hash := strings.ToLower(restrictions.OldPasswordHash)
+		if len(hash) < minOldPasswordHashLength || len(hash) > 2*sha1.Size || strings.Trim(hash, "0123456789abcdef") != "" {
+			return fmt.Errorf("Parameter oldPasswordHash has to be the hex sha1 hash of the old password or its first %d or more characters", minOldPasswordHashLength)
+		}
+		restrictions.OldPasswordHash = hash
+	}
+	if restrictions.OldPassword == "" {
+		for _, parameter := range []string{"minPasswordDistance", "maxCommonSubstring"} {
+			if query.Has(parameter) {
+				restrictions.warn("Parameter " + parameter + " ignored because oldPassword isn't set")
+			}
+		}
+		return nil
+	}
+	if !query.Has("minPasswordDistance") {
+		restrictions.MinPasswordDistance = defaultMinPasswordDistance
+	}
+	if !query.Has("maxCommonSubstring") {
+		restrictions.MaxCommonSubstring = defaultMaxCommonSubstring
+	}
+	if restrictions.MinPasswordDistance < 1 {
+		return errors.New("Parameter minPasswordDistance has to be at least 1")
+	}
+	if restrictions.MaxCommonSubstring < 1 {
+		return errors.New("Parameter maxCommonSubstring has to be at least 1")
+	}
+	return nil
+}
+
+// levenshtein is the number of characters inserted, deleted or substituted
+// to turn a into b.
+func levenshtein(a, b string) int {
+	runesA, runesB := []rune(a), []rune(b)
+	previous := make([]int, len(runesB)+1)
+	current := make([]int, len(runesB)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(runesA); i++ {
+		current[0] = i
+		for j := 1; j <= len(runesB); j++ {
+			cost := 1
+			if runesA[i-1] == runesB[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(runesB)]
+}
+
+// longestCommonSubstring is the longest part of a also found in b, ignoring
+// case, lowercased.
+func longestCommonSubstring(a, b string) string {
+	runesA, runesB := []rune(strings.ToLower(a)), []rune(strings.ToLower(b))
+	previous := make([]int, len(runesB)+1)
+	current := make([]int, len(runesB)+1)
+	longest, end := 0, 0
+	for i := 1; i <= len(runesA); i++ {
+		for j := 1; j <= len(runesB); j++ {
+			current[j] = 0
+			if runesA[i-1] == runesB[j-1] {
+				current[j] = previous[j-1] + 1
+				if current[j] > longest {
+					longest, end = current[j], i
+				}
+			}
+		}
+		previous, current = current, previous
+	}
+	return string(runesA[end-longest : end])
+}
+
+func oldPasswordViolations(password string, restrictions PasswordRestrictions) []string {
+	var violations []string
+	if restrictions.OldPassword != "" {
+		if distance := levenshtein(password, restrictions.OldPassword); distance < restrictions.MinPasswordDistance {
+			violations = append(violations, fmt.Sprintf("edit distance %d from oldPassword, less than minPasswordDistance (%d)", distance, restrictions.MinPasswordDistance))
+		}
+		if common := longestCommonSubstring(password, restrictions.OldPassword); utf8.RuneCountInString(common) > restrictions.MaxCommonSubstring {
+			violations = append(violations, fmt.Sprintf("shares '%s' with oldPassword, longer than maxCommonSubstring (%d)", common, restrictions.MaxCommonSubstring))
+		}
+	}
+	if restrictions.OldPasswordHash != "" {
+		hash := sha1.Sum([]byte(password))
+		if strings.HasPrefix(hex.EncodeToString(hash[:]), restrictions.OldPasswordHash) {
+			violations = append(violations, "matches oldPasswordHash")
+		}
+	}
+	return violations
+}
+
+func checkOldPassword(password string, restrictions PasswordRestrictions) error {
+	if len(oldPasswordViolations(password, restrictions)) > 0 {
+		return violation("oldPassword", errors.New("Generated password is too similar to the old password, try again"))
+	}
+	return nil
+}
+
+func oldPasswordProblems(restrictions PasswordRestrictions) []string {
+	if restrictions.OldPassword == "" {
+		return nil
+	}
+	var problems []string
+	for _, affix := range []struct {
+		parameter string
+		value     string
+	}{
+		{"prefix", restrictions.Prefix},
+		{"suffix", restrictions.Suffix},
+	} {
+		if common := longestCommonSubstring(affix.value, restrictions.OldPassword); utf8.RuneCountInString(common) > restrictions.MaxCommonSubstring {
+			problems = append(problems, fmt.Sprintf("%s %q shares %q with oldPassword, longer than maxCommonSubstring (%d)", affix.parameter, affix.value, common, restrictions.MaxCommonSubstring))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"password_gen/random"
+	"strings"
+	"unicode/utf8"
+)
+
+func resolveSpaces(restrictions *PasswordRestrictions) {
+	if restrictions.MinWords > 0 {
+		restrictions.AllowSpaces = true
+	}
+	if !restrictions.AllowSpaces {
+		return
+	}
+	reason := ""
+	switch {
+	case restrictions.UserReadable:
+		reason = "userReadable is set"
+	case restrictions.Mode != "":
+		reason = "mode is set"
+	case restrictions.PassphrasePattern != "":
+		reason = "passphrasePattern is set"
+	case restrictions.Pattern != "":
+		reason = "pattern is set"
+	case restrictions.AllowedChars != "":
+		reason = "allowedChars is set"
+	case restrictions.GroupSize > 0:
+		reason = "groupSize is set"
+	case restrictions.ClassSpacing > 0:
+		reason = "classSpacing is set"
+	case strings.Contains(restrictions.excluded(), " "):
+		reason = "excludeChars excludes spaces"
+	}
+	if reason == "" {
+		return
+	}
+	parameter := "allowSpaces"
+	if restrictions.MinWords > 0 {
+		parameter = "minWords"
+	}
+	restrictions.warn(fmt.Sprintf("Parameter %s ignored because %s", parameter, reason))
+	restrictions.AllowSpaces, restrictions.MinWords = false, 0
+}
+
+// spaceFits reports whether a space can replace the character at i, which
+// isn't at the edges or next to another space.
+func spaceFits(password []rune, i int) bool {
+	return i > 0 && i < len(password)-1 && password[i-1] != ' ' && password[i] != ' ' && password[i+1] != ' '
+}
+
+// placeSpaces replaces unprotected characters with spaces as often as a
+// space would be drawn with the other characters, and then until the
+// password has minWords words.
+func placeSpaces(password string, restrictions PasswordRestrictions, protected map[int]bool) (string, error) {
+	runes := []rune(password)
+	start, end := restrictions.generated(len(runes))
+	spaces := 0
+	limit := len(runes)
+	if restrictions.MaxCharOccurrences > 0 {
+		limit = restrictions.MaxCharOccurrences
+	}
+	choices := utf8.RuneCountInString(restrictions.baseCharset()) + 1
+	for i := start; i < end && spaces < limit; i++ {
+		if protected[i] || !spaceFits(runes, i) {
+			continue
+		}
+		n, err := random.Intn(choices)
+		if err != nil {
+			return "", err
+		}
+		if n == 0 {
+			runes[i] = ' '
+			spaces++
+		}
+	}
+	for ; spaces < restrictions.MinWords-1; spaces++ {
+		var fitting []int
+		for i := start; i < end; i++ {
+			if !protected[i] && spaceFits(runes, i) {
+				fitting = append(fitting, i)
+			}
+		}
+		if len(fitting) == 0 {
+			return "", errors.New("Generated password has no room for the spaces of minWords, try again")
+		}
+		n, err := random.Intn(len(fitting))
+		if err != nil {
+			return "", err
+		}
+		runes[fitting[n]] = ' '
+	}
+	return string(runes), nil
+}
+
+// spaceViolations are the spaces of password at the edges or doubled, and
+// the words missing for minWords.
+func spaceViolations(password string, restrictions PasswordRestrictions) []string {
+	var violations []string
+	if strings.HasPrefix(password, " ") || strings.HasSuffix(password, " ") {
+		violations = append(violations, "space at the start or end")
+	}
+	if strings.Contains(password, "  ") {
+		violations = append(violations, "doubled spaces")
+	}
+	if words := len(strings.Fields(password)); words < restrictions.MinWords {
+		violations = append(violations, fmt.Sprintf("%d of minWords (%d)", words, restrictions.MinWords))
+	}
+	return violations
+}
+
+func checkSpaces(password string, restrictions PasswordRestrictions) error {
+	if len(spaceViolations(password, restrictions)) > 0 {
+		return violation("spaces", errors.New("Generated password has misplaced spaces or too few words, try again"))
+	}
+	return nil
+}
+
+func spaceProblems(restrictions PasswordRestrictions) []string {
+	if restrictions.MinWords < 2 {
+		return nil
+	}
+	var problems []string
+	spaces := restrictions.MinWords - 1
+	if needed := 2*restrictions.MinWords - 1; needed > restrictions.MaxLength-len(restrictions.Prefix)-len(restrictions.Suffix) {
+		problems = append(problems, fmt.Sprintf("minWords (%d) needs at least %d generated characters, which don't fit in maxLength (%d)", restrictions.MinWords, needed, restrictions.MaxLength))
+	}
+	required := 0
+	for _, requirement := range groupRequirements(restrictions) {
+		required += max(requirement.count, 0)
+	}
+	if required > 0 && required+spaces > restrictions.MaxLength {
+		problems = append(problems, fmt.Sprintf("group minimums and the %d spaces of minWords (%d) require %d characters together, which doesn't fit in maxLength (%d)", spaces, restrictions.MinWords, required+spaces, restrictions.MaxLength))
+	}
+	if restrictions.MaxCharOccurrences > 0 && spaces > restrictions.MaxCharOccurrences {
+		problems = append(problems, fmt.Sprintf("minWords (%d) needs %d spaces, more than maxCharOccurrences (%d) allows", restrictions.MinWords, spaces, restrictions.MaxCharOccurrences))
+	}
+	return problems
+}
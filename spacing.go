@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"password_gen/random"
+	"strings"
+	"unicode"
+)
+
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch)
+}
+
+func isSpacedOut(password string, spacing int) bool {
+	last := -spacing - 1
+	for i, ch := range []rune(password) {
+		if isLetter(ch) {
+			continue
+		}
+		if i-last <= spacing {
+			return false
+		}
+		last = i
+	}
+	return true
+}
+
+// spacedCharset is charset without the digits and special characters which
+// would be closer than classSpacing to another one at position i, so that
+// repairs replacing characters keep the password spaced out.
+func (restrictions PasswordRestrictions) spacedCharset(password []rune, i int, charset string) string {
+	spacing := restrictions.ClassSpacing
+	if spacing <= 0 {
+		return charset
+	}
+	for j := max(0, i-spacing); j <= min(len(password)-1, i+spacing); j++ {
+		if j != i && !isLetter(password[j]) {
+			return strings.Map(func(ch rune) rune {
+				if isLetter(ch) {
+					return ch
+				}
+				return -1
+			}, charset)
+		}
+	}
+	return charset
+}
+
+func spaceCharacterClasses(password string, restrictions PasswordRestrictions) (string, error) {
+	spacing := restrictions.ClassSpacing
+	if isSpacedOut(password, spacing) {
+		return password, nil
+	}
+
+	runes := []rune(password)
+	var letters, others []rune
+	digits, specials := 0, 0
+	for _, ch := range runes {
+		switch {
+		case isLetter(ch):
+			letters = append(letters, ch)
+		case isDigit(ch):
+			others = append(others, ch)
+			digits++
+		default:
+			others = append(others, ch)
+			specials++
+		}
+	}
+
+	for len(others) > 0 && (len(others)-1)*(spacing+1)+1 > len(runes) {
+		var surplus []int
+		for i, ch := range others {
+			digit := isDigit(ch)
+			if (digit && digits > restrictions.MinDigits) || (!digit && specials > restrictions.MinSpecialChars) {
+				surplus = append(surplus, i)
+			}
+		}
+		if len(surplus) == 0 {
+			return "", errors.New("Required characters can't be spaced out in a password of this length, try again")
+		}
+		i, err := random.Intn(len(surplus))
+		if err != nil {
+			return "", err
+		}
+		if isDigit(others[surplus[i]]) {
+			digits--
+		} else {
+			specials--
+		}
+		others = append(others[:surplus[i]], others[surplus[i]+1:]...)
+		letter, err := randomRune(restrictions.charset(restrictions.lower()))
+		if err != nil {
+			return "", err
+		}
+		letters = append(letters, letter)
+	}
+
+	for i := len(others) - 1; i > 0; i-- {
+		j, err := random.Intn(i + 1)
+		if err != nil {
+			return "", err
+		}
+		others[i], others[j] = others[j], others[i]
+	}
+
+	slots := len(runes) - (len(others)-1)*spacing
+	positions := make(map[int]bool, len(others))
+	for chosen, i := 0, 0; chosen < len(others); i++ {
+		n, err := random.Intn(slots - i)
+		if err != nil {
+			return "", err
+		}
+		if n < len(others)-chosen {
+			positions[i+chosen*spacing] = true
+			chosen++
+		}
+	}
+
+	spaced := make([]rune, 0, len(runes))
+	for i := 0; i < len(runes); i++ {
+		if positions[i] {
+			spaced, others = append(spaced, others[0]), others[1:]
+		} else {
+			spaced, letters = append(spaced, letters[0]), letters[1:]
+		}
+	}
+	return string(spaced), nil
+}
@@ -0,0 +1,17 @@
+package main
+
+const (
+	// UnspellableChars are hard to dictate or tell apart when read aloud:
+	// the ambiguous characters, quotes, brackets and most punctuation. The
+	// hyphen separates the groups of spellable passwords.
+	UnspellableChars = AmbiguousChars + "`'\"\\~^()_-{}[]:;<>,./"
+
+	spellableGroupSize = 4
+	spellableSeparator = "-"
+)
+
+// spellable tells whether spellable applies, passphrases are made of words
+// which are dictated as they are.
+func (restrictions PasswordRestrictions) spellable() bool {
+	return restrictions.Spellable && restrictions.Mode != "passphrase" && restrictions.PassphrasePattern == ""
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+func handlePasswordGenStream(w http.ResponseWriter, r *http.Request) {
+	query, err := requestQuery(r)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	restrictions, err := parseRestrictions(query)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	composition, err := parseComposition(query)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		handleError(w, r, errors.New("Streaming is not supported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(200)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	for r.Context().Err() == nil {
+		password, metadata, err := generateWithinBudget(restrictions)
+		if err != nil {
+			encoder.Encode(errorResponse(err))
+			return
+		}
+		response := Response{Error: "", Password: password, Warnings: restrictions.warnings, Metadata: &metadata, PolicyApplied: &restrictions}
+		if composition {
+			described := describeComposition(password)
+			response.Composition = &described
+		}
+		if err := encoder.Encode(response); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
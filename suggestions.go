@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// suggestedLength is the length under which scored passwords are suggested
+// to be lengthened.
+const suggestedLength = 12
+
+var numberWords = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten"}
+
+func numberWord(n int) string {
+	if n < len(numberWords) {
+		return numberWords[n]
+	}
+	return strconv.Itoa(n)
+}
+
+// sequenceTokens are the runs of 3 characters in the alphabet, the digits or
+// a qwerty keyboard row found in password.
+func sequenceTokens(password string) []string {
+	runes := []rune(password)
+	restrictions := PasswordRestrictions{KeyboardLayout: defaultKeyboardLayout, MaxSequenceLength: defaultMaxSequenceLength}
+	var tokens []string
+	scanSequences(runes, restrictions, func(i int) bool {
+		tokens = append(tokens, string(runes[i-2:i+1]))
+		return true
+	})
+	return tokens
+}
+
+// suggestImprovements explains the weaknesses found by scoring the password
+// as suggestions for end users, e.g. "Add two more characters".
+func suggestImprovements(password string, score ScoreResponse) []string {
+	var suggestions []string
+	if score.Breached != nil && *score.Breached {
+		suggestions = append(suggestions, "Choose another password, this one appeared in a data breach")
+	}
+	if length := utf8.RuneCountInString(password); length == suggestedLength-1 {
+		suggestions = append(suggestions, "Add one more character")
+	} else if length < suggestedLength {
+		suggestions = append(suggestions, fmt.Sprintf("Add %s more characters", numberWord(suggestedLength-length)))
+	}
+	avoided := map[string]bool{}
+	for _, term := range blockedTerms(password) {
+		avoided[term] = true
+		suggestions = append(suggestions, fmt.Sprintf("Avoid '%s', your organization blocks it", term))
+	}
+	// A password cracked by a rule is explained by its word, short words
+	// found in otherwise strong passwords aren't worth avoiding.
+	if score.Crack != nil {
+		suggestions = append(suggestions, fmt.Sprintf("Avoid the word '%s', cracking tools try its variations first", score.Crack.Word))
+	} else if score.Dictionary != nil && score.Zxcvbn < 3 {
+		for _, match := range score.Dictionary.Matches {
+			if avoided[match.Word] || utf8.RuneCountInString(match.Word) < 4 {
+				continue
+			}
+			avoided[match.Word] = true
+			if match.Leet {
+				suggestions = append(suggestions, fmt.Sprintf("Avoid the word '%s', writing it as '%s' doesn't hide it", match.Word, match.Token))
+			} else {
+				suggestions = append(suggestions, fmt.Sprintf("Avoid the word '%s'", match.Word))
+			}
+		}
+	}
+	runes := []rune(password)
+	for i := range runes {
+		if run := consecutiveRun(runes, i); run == 3 {
+			suggestions = append(suggestions, fmt.Sprintf("Avoid repeating characters like '%s'", string(runes[i-2:i+1])))
+			break
+		}
+	}
+	if tokens := sequenceTokens(password); len(tokens) > 0 {
+		suggestions = append(suggestions, fmt.Sprintf("Avoid sequences like '%s'", tokens[0]))
+	} else if len(score.Walks) > 0 {
+		suggestions = append(suggestions, fmt.Sprintf("Avoid keyboard patterns like '%s'", score.Walks[0].Token))
+	}
+	if score.Zxcvbn < 3 {
+		var missing []string
+		for _, class := range []struct {
+			name    string
+			charset string
+		}{
+			{"upper case letters", UpperLetters},
+			{"lower case letters", Letters},
+			{"digits", Digits},
+			{"special characters", SpecialChars},
+		} {
+			if !strings.ContainsAny(password, class.charset) {
+				missing = append(missing, class.name)
+			}
+		}
+		if len(missing) > 0 {
+			suggestions = append(suggestions, "Mix in "+joinList(missing))
+		}
+	}
+	return suggestions
+}
+
+func joinList(items []string) string {
+	if len(items) == 1 {
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
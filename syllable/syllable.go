@@ -0,0 +1,72 @@
+package syllable
+
+import (
+	"errors"
+	"password_gen/random"
+)
+
+const (
+	Consonants = "bcdfghjklmnprstvwz"
+	Vowels     = "aeiou"
+)
+
+// Patterns are the syllable shapes passwords are composed of, C standing
+// for a consonant and V for a vowel.
+var Patterns = []string{"CV", "CVC", "CVVC"}
+
+type Options struct {
+	Consonants string
+	Vowels     string
+	// Syllables is the exact number of syllables to generate. When it's 0,
+	// syllables are added while the password fits in MaxLength.
+	Syllables int
+	MaxLength int
+}
+
+func randomByte(charset string) (byte, error) {
+	i, err := random.Intn(len(charset))
+	if err != nil {
+		return 0, err
+	}
+	return charset[i], nil
+}
+
+func Syllable(consonants string, vowels string) (string, error) {
+	if consonants == "" || vowels == "" {
+		return "", errors.New("Syllables need at least one consonant and one vowel")
+	}
+	i, err := random.Intn(len(Patterns))
+	if err != nil {
+		return "", err
+	}
+	syllable := make([]byte, 0, 4)
+	for _, kind := range Patterns[i] {
+		charset := consonants
+		if kind == 'V' {
+			charset = vowels
+		}
+		ch, err := randomByte(charset)
+		if err != nil {
+			return "", err
+		}
+		syllable = append(syllable, ch)
+	}
+	return string(syllable), nil
+}
+
+// Generate appends syllables to prefix. It doesn't need a trained model, so
+// it works when the markov chain one is missing.
+func Generate(prefix string, options Options) (string, error) {
+	password := prefix
+	for count := 0; options.Syllables == 0 || count < options.Syllables; count++ {
+		syllable, err := Syllable(options.Consonants, options.Vowels)
+		if err != nil {
+			return "", err
+		}
+		if options.Syllables == 0 && count > 0 && len(password)+len(syllable) > options.MaxLength {
+			break
+		}
+		password += syllable
+	}
+	return password, nil
+}
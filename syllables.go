@@ -0,0 +1,63 @@
+package main
+
+import (
+	"password_gen/random"
+	"password_gen/syllable"
+	"strings"
+)
+
+const readableAttempts = 200
+
+const vowels = "aeiouy"
+
+// countSyllables approximates the syllable count of a readable password
+// as the number of vowel groups in it.
+func countSyllables(password string) int {
+	count := 0
+	inVowel := false
+	for _, r := range strings.ToLower(password) {
+		isVowel := strings.ContainsRune(vowels, r)
+		if isVowel && !inVowel {
+			count++
+		}
+		inVowel = isVowel
+	}
+	return count
+}
+
+func (restrictions PasswordRestrictions) syllablesFit(password string) bool {
+	count := countSyllables(password)
+	if count < restrictions.MinSyllables {
+		return false
+	}
+	return restrictions.MaxSyllables == 0 || count <= restrictions.MaxSyllables
+}
+
+// syllableCount picks the number of syllables the syllable engine
+// generates, 0 filling up to maxLength when no bounds are set.
+func (restrictions PasswordRestrictions) syllableCount() (int, error) {
+	low, high := restrictions.MinSyllables, restrictions.MaxSyllables
+	switch {
+	case low == 0 && high == 0:
+		return 0, nil
+	case high == 0:
+		high = low + 2
+	case low == 0:
+		low = 1
+	}
+	n, err := random.Intn(high - low + 1)
+	return low + n, err
+}
+
+func generateSyllablePassword(prefix string, restrictions PasswordRestrictions) (string, error) {
+	count, err := restrictions.syllableCount()
+	if err != nil {
+		return "", err
+	}
+	return syllable.Generate(prefix, syllable.Options{
+		Consonants: restrictions.charset(syllable.Consonants),
+		Vowels:     restrictions.charset(syllable.Vowels),
+		Syllables:  count,
+		MaxLength:  restrictions.MaxLength,
+	})
+}
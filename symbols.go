@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+var symbolSets = map[string]string{
+	"full":       SpecialChars,
+	"safe":       "!@#$%&*-_+=?",
+	"url-safe":   "-._~",
+	"shell-safe": "%+,-./:=@_",
+}
+
+// resolveSymbols validates the symbols set name and customSymbols, which
+// overrides it.
+func resolveSymbols(restrictions *PasswordRestrictions, query url.Values) error {
+	if _, ok := symbolSets[restrictions.Symbols]; restrictions.Symbols != "" && !ok {
+		names := make([]string, 0, len(symbolSets))
+		for name := range symbolSets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("Parameter symbols has to be one of %s, not %q", strings.Join(names, ", "), restrictions.Symbols)
+	}
+	if !query.Has("customSymbols") {
+		return nil
+	}
+	if restrictions.CustomSymbols == "" {
+		return errors.New("Parameter customSymbols can't be empty")
+	}
+	for _, r := range restrictions.CustomSymbols {
+		if r < '!' || r > '~' || strings.ContainsRune(Letters+UpperLetters+Digits, r) {
+			return errors.New("Parameter customSymbols can only contain printable ASCII characters other than letters and digits")
+		}
+	}
+	if restrictions.Symbols != "" {
+		restrictions.warn("Parameter symbols ignored because customSymbols is set")
+	}
+	return nil
+}
+
+// specials are the special characters passwords are generated with.
+func (restrictions PasswordRestrictions) specials() string {
+	if restrictions.CustomSymbols != "" {
+		return uniqueChars(restrictions.CustomSymbols)
+	}
+	if set, ok := symbolSets[restrictions.Symbols]; ok {
+		return set
+	}
+	return SpecialChars
+}
+
+// classCharset is the permitted charset of the class of ch.
+func (restrictions PasswordRestrictions) classCharset(ch rune) string {
+	class := characterClass(ch)
+	if class == SpecialChars {
+		class = restrictions.specials()
+	}
+	return restrictions.charset(class)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var placeholderRegexp = regexp.MustCompile(`\{\{\s*password\s+"([^"]*)"((?:\s+\w+=(?:"(?:[^"\\]|\\.)*"|[^\s"}]+))*)\s*\}\}`)
+var anyPlaceholderRegexp = regexp.MustCompile(`\{\{\s*password\b`)
+var placeholderArgumentRegexp = regexp.MustCompile(`(\w+)=("(?:[^"\\]|\\.)*"|[^\s"}]+)`)
+
+type renderedSecret struct {
+	arguments string
+	password  string
+}
+
+func placeholderQuery(arguments string) (url.Values, error) {
+	query := url.Values{}
+	for _, match := range placeholderArgumentRegexp.FindAllStringSubmatch(arguments, -1) {
+		value := match[2]
+		if strings.HasPrefix(value, "\"") {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid value of %s: %w", match[1], err)
+			}
+			value = unquoted
+		}
+		query.Set(match[1], value)
+	}
+	return query, nil
+}
+
+// templateSymbols are the symbols of template secrets which don't choose
+// theirs. Dotenv parsers cut unquoted values at #, expand them at $ and read
+// quotes and backslashes, none of which the shell-safe symbols contain.
+const templateSymbols = "shell-safe"
+
+func defaultTemplateSymbols(query url.Values) {
+	for _, parameter := range []string{"symbols", "customSymbols"} {
+		if _, ok := config.Defaults[parameter]; ok || query.Has(parameter) {
+			return
+		}
+	}
+	query.Set("symbols", templateSymbols)
+}
+
+func checkPlaceholders(template string) error {
+	valid := make(map[int]bool)
+	for _, match := range placeholderRegexp.FindAllStringIndex(template, -1) {
+		valid[match[0]] = true
+	}
+	for _, match := range anyPlaceholderRegexp.FindAllStringIndex(template, -1) {
+		if !valid[match[0]] {
+			return fmt.Errorf("Malformed password placeholder on line %d", strings.Count(template[:match[0]], "\n")+1)
+		}
+	}
+	return nil
+}
+
+func renderTemplate(template string) (string, error) {
+	if err := checkPlaceholders(template); err != nil {
+		return "", err
+	}
+	secrets := make(map[string]renderedSecret)
+	var renderErr error
+
+	rendered := placeholderRegexp.ReplaceAllStringFunc(template, func(placeholder string) string {
+		if renderErr != nil {
+			return placeholder
+		}
+		match := placeholderRegexp.FindStringSubmatch(placeholder)
+		name, arguments := match[1], strings.TrimSpace(match[2])
+		if secret, ok := secrets[name]; ok {
+			if secret.arguments != arguments {
+				renderErr = fmt.Errorf("Password %q is used with different parameters", name)
+			}
+			return secret.password
+		}
+
+		query, err := placeholderQuery(arguments)
+		if err != nil {
+			renderErr = fmt.Errorf("Password %q: %w", name, err)
+			return placeholder
+		}
+		defaultTemplateSymbols(query)
+		restrictions, err := parseRestrictions(query)
+		if err != nil {
+			renderErr = fmt.Errorf("Password %q: %w", name, err)
+			return placeholder
+		}
+		password, _, err := generateWithinBudget(restrictions)
+		if err != nil {
+			renderErr = fmt.Errorf("Password %q: %w", name, err)
+			return placeholder
+		}
+		secrets[name] = renderedSecret{arguments: arguments, password: password}
+		return password
+	})
+	if renderErr != nil {
+		return "", renderErr
+	}
+	return rendered, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderTemplateDefaultsToDotenvSafeSymbols(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		rendered, err := renderTemplate(`SECRET={{password "secret" maxLength=40 minSpecialChars=10}}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value := strings.TrimPrefix(rendered, "SECRET="); strings.ContainsAny(value, "#$\"'\\` ") {
+			t.Fatalf("Template secret %q isn't dotenv safe", value)
+		}
+	}
+	rendered, err := renderTemplate(`SECRET={{password "secret" maxLength=40 customSymbols="#"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Trim(strings.TrimPrefix(rendered, "SECRET="), Letters+UpperLetters+Digits+"#") != "" {
+		t.Errorf("customSymbols of the placeholder aren't used in %q", rendered)
+	}
+}
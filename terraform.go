@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+)
+
+func runTerraformExternal(stdin io.Reader, stdout io.Writer) error {
+	var query map[string]string
+	if err := json.NewDecoder(stdin).Decode(&query); err != nil {
+		return errors.New("Terraform external query has to be a json object with string values")
+	}
+	values := url.Values{}
+	for key, value := range query {
+		values.Set(key, value)
+	}
+
+	restrictions, err := parseRestrictions(values)
+	if err != nil {
+		return err
+	}
+	password, _, err := generateWithinBudget(restrictions)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(stdout)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(map[string]string{"password": password})
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+const (
+	randomTimeout   = "random"
+	readableTimeout = "readable"
+	scoreTimeout    = "score"
+)
+
+var timeoutClasses = []string{randomTimeout, readableTimeout, scoreTimeout}
+
+func timeoutClass(class string, r *http.Request) string {
+	if class != randomTimeout {
+		return class
+	}
+	query := config.withDefaults(r.URL.Query())
+	if readable, _ := strconv.ParseBool(query.Get("userReadable")); readable {
+		return readableTimeout
+	}
+	return class
+}
+
+func withTimeout(class string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		timeout := config.Timeouts[timeoutClass(class, r)].Duration
+		if timeout <= 0 {
+			next(w, r)
+			return
+		}
+		body, _ := json.Marshal(Response{Error: "Request timed out, try again", Password: ""})
+		w.Header().Set("Content-Type", "application/json")
+		http.TimeoutHandler(next, timeout, string(body)).ServeHTTP(w, r)
+	}
+}
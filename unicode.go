@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// unicodeRangeSets are the characters unicodeRanges adds to the character
+// pool, named after their Unicode blocks. Soft hyphen and the unassigned
+// final sigma capital are left out.
+var unicodeRangeSets = map[string]string{
+	"latin1":   runeRange(0xA1, 0xAC) + runeRange(0xAE, 0xFF),
+	"greek":    runeRange(0x391, 0x3A1) + runeRange(0x3A3, 0x3A9) + runeRange(0x3B1, 0x3C9),
+	"cyrillic": "Ё" + runeRange(0x410, 0x44F) + "ё",
+}
+
+func runeRange(from, to rune) string {
+	var sb strings.Builder
+	for r := from; r <= to; r++ {
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func resolveUnicodeRanges(restrictions *PasswordRestrictions) error {
+	if restrictions.UnicodeRanges == "" {
+		return nil
+	}
+	reason := ""
+	switch {
+	case restrictions.UserReadable:
+		reason = "userReadable is set"
+	case restrictions.Mode != "":
+		reason = "mode is set"
+	case restrictions.PassphrasePattern != "":
+		reason = "passphrasePattern is set"
+	case restrictions.Pattern != "":
+		reason = "pattern is set"
+	case restrictions.AllowedChars != "":
+		reason = "allowedChars is set"
+	}
+	if reason != "" {
+		restrictions.warn("Parameter unicodeRanges ignored because " + reason)
+		restrictions.UnicodeRanges = ""
+		return nil
+	}
+	for _, name := range strings.Split(restrictions.UnicodeRanges, ",") {
+		if _, ok := unicodeRangeSets[strings.TrimSpace(name)]; !ok {
+			return fmt.Errorf("Parameter unicodeRanges has to list latin1, greek or cyrillic, not %q", name)
+		}
+	}
+	return nil
+}
+
+// unicodeChars are the characters of the ranges listed in unicodeRanges.
+func (restrictions PasswordRestrictions) unicodeChars() string {
+	if restrictions.UnicodeRanges == "" {
+		return ""
+	}
+	var chars string
+	for _, name := range strings.Split(restrictions.UnicodeRanges, ",") {
+		chars += unicodeRangeSets[strings.TrimSpace(name)]
+	}
+	return uniqueChars(chars)
+}
+
+// countedSpecials are the characters counting as special: the symbols and
+// the characters of unicodeRanges which aren't letters or digits, like ¶,
+// as classPositions counts them towards maxSpecialChars.
+func (restrictions PasswordRestrictions) countedSpecials() string {
+	specials := restrictions.specials()
+	for _, ch := range restrictions.unicodeChars() {
+		if characterClass(ch) == SpecialChars {
+			specials += string(ch)
+		}
+	}
+	return specials
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"password_gen/dictionary"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minUserInputLength is the least length of a user input, or a part of one,
+// that passwords can't contain. Shorter ones match too many passwords.
+const minUserInputLength = 3
+
+// resolveUserInputs splits userInputs into the tokens passwords can't
+// contain: every input and its parts, split on characters other than letters
+// and digits. The top level domain of an email isn't a part, so that
+// john.smith@acme.com is john.smith@acme.com, john, smith and acme.
+func resolveUserInputs(restrictions *PasswordRestrictions) {
+	seen := map[string]bool{}
+	for _, input := range strings.Split(restrictions.UserInputs, ",") {
+		input = strings.ToLower(strings.TrimSpace(input))
+		stripped := input
+		if at := strings.Index(input, "@"); at >= 0 {
+			if dot := strings.LastIndex(input, "."); dot > at {
+				stripped = input[:dot]
+			}
+		}
+		parts := strings.FieldsFunc(stripped, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, token := range append([]string{input}, parts...) {
+			if utf8.RuneCountInString(token) >= minUserInputLength && !seen[token] {
+				seen[token] = true
+				restrictions.userTokens = append(restrictions.userTokens, token)
+			}
+		}
+	}
+}
+
+// userInputsIn are the user inputs password contains, ignoring case, forwards
+// or reversed and reading leet substitutes as letters.
+func userInputsIn(password string, restrictions PasswordRestrictions) []string {
+	if len(restrictions.userTokens) == 0 {
+		return nil
+	}
+	var found []string
+	for _, token := range restrictions.userTokens {
+		reversed := []rune(token)
+		slices.Reverse(reversed)
+		for _, variant := range []string{token, dictionary.Unleet(token), string(reversed), dictionary.Unleet(string(reversed))} {
+			if dictionary.ContainsLeet(password, variant) {
+				found = append(found, token)
+				break
+			}
+		}
+	}
+	return found
+}
+
+func checkUserInputs(password string, restrictions PasswordRestrictions) error {
+	if len(userInputsIn(password, restrictions)) > 0 {
+		return violation("userInputs", errors.New("Generated password contains a user input, try again"))
+	}
+	return nil
+}
+
+func userInputViolations(password string, restrictions PasswordRestrictions) []string {
+	var violations []string
+	for _, token := range userInputsIn(password, restrictions) {
+		violations = append(violations, fmt.Sprintf("contains the user input '%s'", token))
+	}
+	return violations
+}
+
+func userInputProblems(restrictions PasswordRestrictions) []string {
+	var problems []string
+	for _, affix := range []struct {
+		parameter string
+		value     string
+	}{
+		{"prefix", restrictions.Prefix},
+		{"suffix", restrictions.Suffix},
+	} {
+		if found := userInputsIn(affix.value, restrictions); len(found) > 0 {
+			problems = append(problems, fmt.Sprintf("%s %q contains the user input %q", affix.parameter, affix.value, found[0]))
+		}
+	}
+	return problems
+}
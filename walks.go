@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"password_gen/keyboard"
+)
+
+// resolveKeyboardWalks validates minWalkLength and defaults it when
+// noKeyboardWalks is set.
+func resolveKeyboardWalks(restrictions *PasswordRestrictions, query url.Values) error {
+	if !restrictions.NoKeyboardWalks {
+		if query.Has("minWalkLength") {
+			restrictions.warn("Parameter minWalkLength ignored because noKeyboardWalks isn't set")
+		}
+		return nil
+	}
+	if !query.Has("minWalkLength") {
+		restrictions.MinWalkLength = keyboard.MinWalkLength
+	}
+	if restrictions.MinWalkLength < 3 {
+		return errors.New("Parameter minWalkLength has to be at least 3")
+	}
+	return nil
+}
+
+func checkKeyboardWalks(password string, restrictions PasswordRestrictions) error {
+	if len(keyboard.Walks(password, restrictions.MinWalkLength)) > 0 {
+		return violation("keyboardWalks", errors.New("Generated password contains a keyboard walk, try again"))
+	}
+	return nil
+}
+
+func keyboardWalkViolations(password string, restrictions PasswordRestrictions) []string {
+	var violations []string
+	for _, walk := range keyboard.Walks(password, restrictions.MinWalkLength) {
+		violations = append(violations, fmt.Sprintf("contains the keyboard walk '%s'", walk.Token))
+	}
+	return violations
+}
+
+func keyboardWalkProblems(restrictions PasswordRestrictions) []string {
+	if !restrictions.NoKeyboardWalks {
+		return nil
+	}
+	var problems []string
+	for _, affix := range []struct {
+		parameter string
+		value     string
+	}{
+		{"prefix", restrictions.Prefix},
+		{"suffix", restrictions.Suffix},
+	} {
+		if walks := keyboard.Walks(affix.value, restrictions.MinWalkLength); len(walks) > 0 {
+			problems = append(problems, fmt.Sprintf("%s %q contains the keyboard walk %q", affix.parameter, affix.value, walks[0].Token))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+func messageToQuery(message []byte) (url.Values, error) {
+	var fields map[string]any
+	if err := json.Unmarshal(message, &fields); err != nil {
+		return nil, errors.New("Message has to be a json object with password restrictions")
+	}
+	query := url.Values{}
+	for key, value := range fields {
+		query.Set(key, fmt.Sprint(value))
+	}
+	return query, nil
+}
+
+func handleWebSocketMessage(message []byte) Response {
+	query, err := messageToQuery(message)
+	if err != nil {
+		return Response{Error: err.Error(), Password: ""}
+	}
+	restrictions, err := parseRestrictions(query)
+	if err != nil {
+		return Response{Error: err.Error(), Password: ""}
+	}
+	password, metadata, err := generateWithinBudget(restrictions)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return Response{Error: "", Password: password, Warnings: restrictions.warnings, Metadata: &metadata, PolicyApplied: &restrictions}
+}
+
+func handlePasswordGenWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	if config.MaxBodyBytes > 0 {
+		conn.SetReadLimit(config.MaxBodyBytes)
+	}
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Println("websocket:", clientIP(r), err)
+			}
+			return
+		}
+		if err = conn.WriteJSON(handleWebSocketMessage(message)); err != nil {
+			return
+		}
+	}
+}
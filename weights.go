@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"password_gen/random"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// weightedClassNames name the characterClasses in classWeights.
+var weightedClassNames = []string{"letters", "digits", "specials"}
+
+func resolveClassWeights(restrictions *PasswordRestrictions) error {
+	if restrictions.ClassWeights == "" {
+		return nil
+	}
+	reason := ""
+	switch {
+	case restrictions.UserReadable:
+		reason = "userReadable is set"
+	case restrictions.Mode != "":
+		reason = "mode is set"
+	case restrictions.PassphrasePattern != "":
+		reason = "passphrasePattern is set"
+	case restrictions.Pattern != "":
+		reason = "pattern is set"
+	}
+	if reason != "" {
+		restrictions.warn("Parameter classWeights ignored because " + reason)
+		restrictions.ClassWeights = ""
+		return nil
+	}
+	if restrictions.Balanced {
+		return errors.New("Parameter classWeights can't be combined with balanced")
+	}
+
+	weights := make([]int, len(weightedClassNames))
+	listed := map[string]bool{}
+	total := 0
+	for _, entry := range strings.Split(restrictions.ClassWeights, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(entry), "=")
+		weight, err := strconv.Atoi(value)
+		if !found || err != nil {
+			return fmt.Errorf("Parameter classWeights has to list weights like letters=70,digits=20,specials=10, not %q", entry)
+		}
+		class := -1
+		for i, className := range weightedClassNames {
+			if name == className {
+				class = i
+			}
+		}
+		if class < 0 {
+			return fmt.Errorf("Parameter classWeights can only weigh letters, digits and specials, not %q", name)
+		}
+		if listed[name] {
+			return fmt.Errorf("Parameter classWeights lists %s twice", name)
+		}
+		listed[name] = true
+		if weight < 0 {
+			return fmt.Errorf("Parameter classWeights can't have a negative weight for %s", name)
+		}
+		weights[class] = weight
+		total += weight
+	}
+	if total == 0 {
+		return errors.New("Parameter classWeights needs a positive weight")
+	}
+	restrictions.weights = weights
+	return nil
+}
+
+// weightedClasses are the characterClasses with their weights, leaving out
+// the ones without weight or permitted characters.
+func (restrictions PasswordRestrictions) weightedClasses() ([]string, []int) {
+	var classes []string
+	var weights []int
+	for i, class := range restrictions.characterClasses() {
+		if class != "" && restrictions.weights[i] > 0 {
+			classes = append(classes, class)
+			weights = append(weights, restrictions.weights[i])
+		}
+	}
+	return classes, weights
+}
+
+// weightedEntropy is the entropy of a character drawn by its class weight
+// and then uniformly from the class.
+func (restrictions PasswordRestrictions) weightedEntropy() float64 {
+	classes, weights := restrictions.weightedClasses()
+	total := 0
+	for _, weight := range weights {
+		total += weight
+	}
+	bits := 0.0
+	for i, class := range classes {
+		p := float64(weights[i]) / float64(total)
+		bits += p * (math.Log2(float64(utf8.RuneCountInString(class))) - math.Log2(p))
+	}
+	return bits
+}
+
+// generateWeightedPassword draws the class of every character in proportion
+// to classWeights, and the character uniformly from its class.
+func generateWeightedPassword(length int, restrictions PasswordRestrictions) (string, error) {
+	classes, weights := restrictions.weightedClasses()
+	total := 0
+	for _, weight := range weights {
+		total += weight
+	}
+	if total == 0 || length <= 0 {
+		return "", nil
+	}
+	password := make([]rune, 0, length)
+	for i := 0; i < length; i++ {
+		n, err := random.Intn(total)
+		if err != nil {
+			return "", err
+		}
+		class := 0
+		for n >= weights[class] {
+			n -= weights[class]
+			class++
+		}
+		ch, err := randomRune(classes[class])
+		if err != nil {
+			return "", err
+		}
+		password = append(password, ch)
+	}
+	return string(password), nil
+}
+
+func classWeightProblems(restrictions PasswordRestrictions) []string {
+	if restrictions.weights == nil {
+		return nil
+	}
+	if classes, _ := restrictions.weightedClasses(); len(classes) == 0 {
+		return []string{"classWeights only weighs classes allowedChars, excludeChars or symbols leave without characters"}
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"password_gen/passphrase"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	minWordlistWords   = 256
+	maxWordlistWordLen = 32
+)
+
+var wordlistName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+type WordlistInfo struct {
+	Name  string `json:"name"`
+	Words int    `json:"words"`
+}
+
+type WordlistResponse struct {
+	Error    string        `json:"error"`
+	Wordlist *WordlistInfo `json:"wordlist,omitempty"`
+}
+
+type WordlistsResponse struct {
+	Error     string         `json:"error"`
+	Wordlists []WordlistInfo `json:"wordlists"`
+}
+
+var errNoWordlistDir = statusError{status: http.StatusNotFound, message: "Wordlists aren't configured, set wordlistDir"}
+
+func (c Config) wordlistPath(name string) (string, error) {
+	if c.WordlistDir == "" {
+		return "", errNoWordlistDir
+	}
+	if !wordlistName.MatchString(name) {
+		return "", fmt.Errorf("Wordlist name %q can only contain lowercase letters, digits, - and _", name)
+	}
+	return filepath.Join(c.WordlistDir, name+".txt"), nil
+}
+
+func (c Config) loadWordlist(name string) ([]string, error) {
+	path, err := c.wordlistPath(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, statusError{status: http.StatusNotFound, message: fmt.Sprintf("Wordlist %s doesn't exist", name)}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Wordlist %s can't be read", name)
+	}
+	return passphrase.ParseWordlist(string(data)), nil
+}
+
+func validateWordlist(words []string) error {
+	var problems []string
+	if len(words) < minWordlistWords {
+		problems = append(problems, fmt.Sprintf("it has %d words, but at least %d are needed", len(words), minWordlistWords))
+	}
+	seen := map[string]bool{}
+	var invalid, duplicates, profane []string
+	for _, word := range words {
+		if len(word) > maxWordlistWordLen || strings.Trim(word, Letters+UpperLetters) != "" {
+			invalid = append(invalid, word)
+			continue
+		}
+		if seen[strings.ToLower(word)] {
+			duplicates = append(duplicates, word)
+		}
+		seen[strings.ToLower(word)] = true
+		if passphrase.IsProfane(word) {
+			profane = append(profane, word)
+		}
+	}
+	if len(invalid) > 0 {
+		problems = append(problems, fmt.Sprintf("%d words aren't ASCII letters of at most %d characters: %s", len(invalid), maxWordlistWordLen, sample(invalid)))
+	}
+	if len(duplicates) > 0 {
+		problems = append(problems, fmt.Sprintf("%d words are duplicates: %s", len(duplicates), sample(duplicates)))
+	}
+	if len(profane) > 0 {
+		problems = append(problems, fmt.Sprintf("%d words are profane: %s", len(profane), sample(profane)))
+	}
+	if len(problems) > 0 {
+		return errors.New("Wordlist is invalid: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func sample(words []string) string {
+	if len(words) > 5 {
+		return strings.Join(words[:5], ", ") + ", ..."
+	}
+	return strings.Join(words, ", ")
+}
+
+func requireAdminToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if config.AdminToken == "" {
+			handleError(w, r, statusError{status: http.StatusForbidden, message: "Admin endpoints require adminToken to be configured"})
+			return
+		}
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(token), []byte(config.AdminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			handleError(w, r, statusError{status: http.StatusUnauthorized, message: "Authorization with a valid admin token is required"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+func handleListWordlists(w http.ResponseWriter, r *http.Request) {
+	if config.WordlistDir == "" {
+		handleError(w, r, errNoWordlistDir)
+		return
+	}
+	paths, err := filepath.Glob(filepath.Join(config.WordlistDir, "*.txt"))
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	response := WordlistsResponse{Wordlists: []WordlistInfo{}}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".txt")
+		words, err := config.loadWordlist(name)
+		if err != nil {
+			continue
+		}
+		response.Wordlists = append(response.Wordlists, WordlistInfo{Name: name, Words: len(words)})
+	}
+	sort.Slice(response.Wordlists, func(i, j int) bool {
+		return response.Wordlists[i].Name < response.Wordlists[j].Name
+	})
+	writeResponse(w, r, 200, response)
+}
+
+func handlePutWordlist(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	path, err := config.wordlistPath(name)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	if config.MaxBodyBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, config.MaxBodyBytes)
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		handleError(w, r, bodyError(err, "Body can't be read"))
+		return
+	}
+	words := passphrase.ParseWordlist(string(body))
+	if err := validateWordlist(words); err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	temp, err := os.CreateTemp(config.WordlistDir, ".upload-*")
+	if err != nil {
+		handleError(w, r, statusError{status: http.StatusInternalServerError, message: "Wordlist can't be stored"})
+		return
+	}
+	defer os.Remove(temp.Name())
+	_, err = temp.WriteString(strings.Join(words, "\n") + "\n")
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		handleError(w, r, statusError{status: http.StatusInternalServerError, message: "Wordlist can't be stored"})
+		return
+	}
+	writeResponse(w, r, 200, WordlistResponse{Wordlist: &WordlistInfo{Name: name, Words: len(words)}})
+}
+
+func handleDeleteWordlist(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	path, err := config.wordlistPath(name)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	err = os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		handleError(w, r, statusError{status: http.StatusNotFound, message: fmt.Sprintf("Wordlist %s doesn't exist", name)})
+		return
+	}
+	if err != nil {
+		handleError(w, r, statusError{status: http.StatusInternalServerError, message: "Wordlist can't be deleted"})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}